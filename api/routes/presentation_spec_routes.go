@@ -16,11 +16,43 @@ func RegisterPresentationSpecRoutes(s *server.FiberServer, p *presentation_spec_
 	presentationSpecRoutes.Use(middlewares.AuthMiddleware(a))
 
 	presentationSpecRoutes.Get("/", func(c *fiber.Ctx) error {
-		return handlers.GetPresentationSpecHandler(c, p)
+		// a busca da spec de um usuário sempre envia user_company, service e base, sem eles é a listagem
+		if c.Query("user_company") != "" && c.Query("service") != "" && c.Query("base") != "" {
+			return handlers.GetPresentationSpecHandler(c, p)
+		}
+		return handlers.ListPresentationSpecsHandler(c, p)
+	})
+	// /list sempre lista, para filtrar pelos três campos da busca
+	presentationSpecRoutes.Get("/list", func(c *fiber.Ctx) error {
+		return handlers.ListPresentationSpecsHandler(c, p)
 	})
 	presentationSpecRoutes.Post("/", func(c *fiber.Ctx) error {
 		return handlers.AddPresentationSpecHandler(c, p)
 	})
+	presentationSpecRoutes.Post("/validate", func(c *fiber.Ctx) error {
+		return handlers.ValidatePresentationSpecHandler(c)
+	})
+	presentationSpecRoutes.Post("/preview", func(c *fiber.Ctx) error {
+		return handlers.PreviewPresentationSpecHandler(c, p)
+	})
+	presentationSpecRoutes.Post("/import", func(c *fiber.Ctx) error {
+		return handlers.ImportPresentationSpecHandler(c, p)
+	})
+	presentationSpecRoutes.Get("/:id/export", func(c *fiber.Ctx) error {
+		return handlers.ExportPresentationSpecHandler(c, p)
+	})
+	presentationSpecRoutes.Post("/:id/preview", func(c *fiber.Ctx) error {
+		return handlers.PreviewPresentationSpecHandler(c, p)
+	})
+	presentationSpecRoutes.Get("/:id/versions", func(c *fiber.Ctx) error {
+		return handlers.GetPresentationSpecVersionsHandler(c, p)
+	})
+	presentationSpecRoutes.Get("/:id/versions/diff", func(c *fiber.Ctx) error {
+		return handlers.DiffPresentationSpecVersionsHandler(c, p)
+	})
+	presentationSpecRoutes.Post("/:id/rollback/:version", func(c *fiber.Ctx) error {
+		return handlers.RollbackPresentationSpecHandler(c, p)
+	})
 	presentationSpecRoutes.Patch("/source", func(c *fiber.Ctx) error {
 		return handlers.PatchSourceHandler(c, p)
 	})
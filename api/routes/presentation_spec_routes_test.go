@@ -0,0 +1,118 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"export-service/api/routes"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"export-service/internal/gateways"
+	"export-service/internal/repositories/presentation_spec_repo"
+	"export-service/internal/server"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap"
+)
+
+// fakeAuthGateway accepts any token, the other methods are not used by the routes
+type fakeAuthGateway struct {
+	gateways.AuthServiceGateway
+}
+
+func (f fakeAuthGateway) GetUserByToken(headers map[string]any) (gateways.AuthUser, error) {
+	return gateways.AuthUser{Email: "fulano@driva.com.br"}, nil
+}
+
+func TestPresentationSpecRoutes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Setup postgres container
+
+	postgresContainer, err := postgres.Run(ctx,
+		"docker.io/postgres:15-alpine",
+		postgres.WithDatabase("exports"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		postgres.WithInitScripts("../../internal/repositories/presentation_spec_repo/seed.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		log.Fatalf("failed to start container: %s", err)
+	}
+
+	// Clean up the container
+	defer func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			log.Fatalf("failed to terminate container: %s", err)
+		}
+	}()
+	url, _ := postgresContainer.ConnectionString(ctx)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		log.Fatalf("Unable to parse connection string: %v", err)
+	}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		log.Fatalf("Unable to create connection pool: %v", err)
+	}
+	defer conn.Close()
+	logger, _ := zap.NewProduction()
+	repo := presentation_spec_repo.NewPgPresentationSpecRepository(conn, logger)
+
+	s := server.New()
+	routes.RegisterPresentationSpecRoutes(s, repo, fakeAuthGateway{})
+
+	get := func(t *testing.T, target string, result any) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := s.Test(req, -1)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+		return resp.StatusCode
+	}
+
+	// Begin testing
+	t.Run("Should return a single spec when user_email is not sent", func(t *testing.T) {
+		var spec domain.PresentationSpec
+		status := get(t, "/presentation-spec?user_company=Driva&service=enrichment_test&base=empresas", &spec)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "123e4567-e89b-12d3-a456-426655440000", spec.ID)
+	})
+
+	t.Run("Should return a single spec when user_email is empty", func(t *testing.T) {
+		var spec domain.PresentationSpec
+		status := get(t, "/presentation-spec?user_email=&user_company=Driva&service=enrichment_test&base=empresas", &spec)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "123e4567-e89b-12d3-a456-426655440000", spec.ID)
+	})
+
+	t.Run("Should list specs when the search params are incomplete", func(t *testing.T) {
+		var list ports.PresentationSpecList
+		status := get(t, "/presentation-spec?service=enrichment_test", &list)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 2, list.Total)
+	})
+
+	t.Run("Should list specs in their own route", func(t *testing.T) {
+		var list ports.PresentationSpecList
+		status := get(t, "/presentation-spec/list?service=enrichment_test", &list)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 2, list.Total)
+	})
+}
@@ -28,15 +28,18 @@ func RegisterSheetRoutes(s *server.FiberServer, uploader ports.Uploader, specRep
 			return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
 		}
 
-		sheetUc := usecases.NewSheetExportUseCase(&writers.ExcelWriter{}, &adapters.HTTPDownloader{}, uploader, specRepo, mailer, logger)
-		downloadUrl, err := sheetUc.Execute(req)
+		sheetUc := usecases.NewSheetExportUseCase(writers.GetWriters(), &adapters.HTTPDownloader{}, uploader, specRepo, mailer, logger)
+		result, err := sheetUc.Execute(req)
 		if err != nil {
 			logger.Error("Failed to execute use case", zap.Error(err))
 			return c.Status(fiber.StatusInternalServerError).JSON(map[string]any{"error": "error while executing use case"})
 		}
 
 		return c.Status(fiber.StatusCreated).JSON(map[string]any{
-			"download_url": downloadUrl,
+			"download_url":              result.DownloadUrls[0],
+			"download_urls":             result.DownloadUrls,
+			"presentation_spec_id":      result.PresentationSpecID,
+			"presentation_spec_version": result.PresentationSpecVersion,
 		})
 	})
 }
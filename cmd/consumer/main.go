@@ -145,13 +145,14 @@ func handleCrmExportRequest(d amqp.Delivery, conn *pgxpool.Pool) {
 	}
 
 	configs := map[string]any{
-		"crm":            headers["crm"],
-		"pipeline_id":    headers["pipeline_id"],
-		"stage_id":       headers["stage_id"],
-		"owner_id":       headers["owner_id"],
-		"create_deal":    headers["create_deal"],
-		"overwrite_data": headers["overwrite_data"],
-		"total":          total,
+		"crm":             headers["crm"],
+		"pipeline_id":     headers["pipeline_id"],
+		"stage_id":        headers["stage_id"],
+		"owner_id":        headers["owner_id"],
+		"create_deal":     headers["create_deal"],
+		"overwrite_data":  headers["overwrite_data"],
+		"total":           total,
+		"timeout_seconds": headers["timeout_seconds"],
 		//Add other crm configs
 	}
 
@@ -198,20 +199,29 @@ func handleExportRequest(d amqp.Delivery, conn *pgxpool.Pool, client *messaging.
 	}
 
 	sheetUc := getSheetUseCase(logger, conn)
-	downloadUrl, err := sheetUc.Execute(req)
-	publishResult(ctx, client, logger, req, downloadUrl, err)
+	result, err := sheetUc.Execute(req)
+	publishResult(ctx, client, logger, req, result, err)
 
 	failOnError(d.Ack(false), "Failed to ack message")
 }
 
-func publishResult(ctx context.Context, c *messaging.RabbitClient, logger *zap.Logger, req usecases.ExportRequest, downloadUrl string, err error) {
+// download_url keeps the first file for consumers that predate exports split in parts
+func publishResult(ctx context.Context, c *messaging.RabbitClient, logger *zap.Logger, req usecases.ExportRequest, result usecases.ExportResult, err error) {
 	response := struct {
-		ListID      string `json:"list_id,omitempty"`
-		DownloadUrl string `json:"download_url,omitempty"`
-		Error       string `json:"error,omitempty"`
+		ListID                  string   `json:"list_id,omitempty"`
+		DownloadUrl             string   `json:"download_url,omitempty"`
+		DownloadUrls            []string `json:"download_urls,omitempty"`
+		PresentationSpecID      string   `json:"presentation_spec_id,omitempty"`
+		PresentationSpecVersion int      `json:"presentation_spec_version,omitempty"`
+		Error                   string   `json:"error,omitempty"`
 	}{
-		ListID:      req.ListID,
-		DownloadUrl: downloadUrl,
+		ListID:                  req.ListID,
+		DownloadUrls:            result.DownloadUrls,
+		PresentationSpecID:      result.PresentationSpecID,
+		PresentationSpecVersion: result.PresentationSpecVersion,
+	}
+	if len(result.DownloadUrls) > 0 {
+		response.DownloadUrl = result.DownloadUrls[0]
 	}
 
 	if err != nil {
@@ -253,7 +263,7 @@ func getSheetUseCase(logger *zap.Logger, conn *pgxpool.Pool) *usecases.SheetExpo
 	mailer := adapters.NewDrivaMailer(logger)
 
 	specRepo := presentation_spec_repo.NewPgPresentationSpecRepository(conn, logger)
-	return usecases.NewSheetExportUseCase(&writers.ExcelWriter{}, &adapters.HTTPDownloader{}, uploader, specRepo, mailer, logger)
+	return usecases.NewSheetExportUseCase(writers.GetWriters(), &adapters.HTTPDownloader{}, uploader, specRepo, mailer, logger)
 }
 
 func failOnError(err error, msg string) {
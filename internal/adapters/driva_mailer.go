@@ -25,14 +25,19 @@ func NewDrivaMailer(logger *zap.Logger) *DrivaMailer {
 	}
 }
 
-func (d *DrivaMailer) SendEmail(userEmail, userName, templateId, link string) error {
+func (d *DrivaMailer) SendEmail(userEmail, userName, templateId string, links []string) error {
+	if len(links) == 0 {
+		return fmt.Errorf("no link to send")
+	}
+
 	payload := map[string]any{
 		"from":        "dados@driva.io",
 		"to":          userEmail,
 		"template_id": templateId,
-		"dynamicTemplateData": map[string]string{
-			"link": link,
-			"name": userName,
+		"dynamicTemplateData": map[string]any{
+			"link":  links[0], // mantém os templates que só mostram um link
+			"links": links,
+			"name":  userName,
 		},
 	}
 
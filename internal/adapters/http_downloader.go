@@ -12,21 +12,16 @@ type HTTPDownloader struct {
 
 var _ ports.Downloader = (*HTTPDownloader)(nil)
 
-func (h *HTTPDownloader) Download(url string) ([]byte, error) {
+func (h *HTTPDownloader) Download(url string) (io.ReadCloser, error) {
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		resp.Body.Close()
 		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
 	}
 
-	bytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return bytes, nil
+	return resp.Body, nil
 }
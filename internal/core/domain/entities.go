@@ -5,22 +5,83 @@ import (
 	"time"
 )
 
+const (
+	ColumnTypeString  = "string"
+	ColumnTypeNumber  = "number"
+	ColumnTypeDate    = "date"
+	ColumnTypeBoolean = "boolean"
+	ColumnTypeUrl     = "url"
+)
+
+// how a column presented as a list is written, MultiValueFirst is used when none is set
+const (
+	MultiValueFirst   = "first"
+	MultiValueJoin    = "join"
+	MultiValueSpread  = "spread"
+	MultiValueExplode = "explode"
+)
+
+type PresentationSpecColumnOptions struct {
+	Type         string  `json:"type,omitempty"`
+	NumberFormat string  `json:"number_format,omitempty"`
+	Label        string  `json:"label,omitempty"`
+	Width        float64 `json:"width,omitempty"`
+	Hidden       bool    `json:"hidden,omitempty"`
+	MultiValue   string  `json:"multi_value,omitempty"`
+	Separator    string  `json:"separator,omitempty"`
+	MaxValues    int     `json:"max_values,omitempty"`
+}
+
 type PresentationSpecSheetOptions struct {
-	Key           string   `json:"key"`
-	ActiveColumns []string `json:"active_columns"`
-	Position      int      `json:"position"`
-	ShouldExplode bool     `json:"should_explode"`
+	Key           string                                   `json:"key"`
+	ActiveColumns []string                                 `json:"active_columns"`
+	Position      int                                      `json:"position"`
+	ShouldExplode bool                                     `json:"should_explode"`
+	ColumnOptions map[string]PresentationSpecColumnOptions `json:"column_options,omitempty"`
+	HeaderColor   string                                   `json:"header_color,omitempty"`
+	FreezeHeader  bool                                     `json:"freeze_header,omitempty"`
+	AutoFilter    bool                                     `json:"auto_filter,omitempty"`
 }
 
 type PresentationSpecPatchSheetOptions struct {
-	Key           string   `json:"key"`
-	ActiveColumns []string `json:"active_columns"`
-	Position      int      `json:"position"`
-	ShouldExplode bool     `json:"should_explode"`
+	Key           string                                   `json:"key"`
+	ActiveColumns []string                                 `json:"active_columns"`
+	Position      int                                      `json:"position"`
+	ShouldExplode bool                                     `json:"should_explode"`
+	ColumnOptions map[string]PresentationSpecColumnOptions `json:"column_options,omitempty"`
+	HeaderColor   string                                   `json:"header_color,omitempty"`
+	FreezeHeader  bool                                     `json:"freeze_header,omitempty"`
+	AutoFilter    bool                                     `json:"auto_filter,omitempty"`
 }
 
 type PresentationSpecSpec map[string]map[string]any
 
+// PresentationSpecVersion is a snapshot of a spec saved on every change, Spec and SheetOptions are empty in listings.
+// snapshots of inherited specs hold only their overrides, which are resolved against the current parent when read
+type PresentationSpecVersion struct {
+	PresentationSpecID string                         `json:"presentation_spec_id"`
+	Version            int                            `json:"version"`
+	Spec               PresentationSpecSpec           `json:"spec,omitempty"`
+	SheetOptions       []PresentationSpecSheetOptions `json:"sheet_options,omitempty"`
+	RemovedKeys        []string                       `json:"removed_keys,omitempty"`
+	Author             string                         `json:"author"`
+	CreatedAt          time.Time                      `json:"created_at"`
+}
+
+// PresentationSpecChange is a difference between two versions of a spec, Type is added, removed or changed
+type PresentationSpecChange struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// PresentationSpecIssue is a problem found in a spec, located by the dotted path of the value
+type PresentationSpecIssue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
 type PresentationSpec struct {
 	ID           string                         `json:"id" binding:"required"`
 	Version      int                            `json:"version"  binding:"required"`
@@ -33,6 +94,22 @@ type PresentationSpec struct {
 	CreatedAt    time.Time                      `json:"created_at"  binding:"required"`
 	UpdatedAt    time.Time                      `json:"updated_at"  binding:"required"`
 	IsDefault    bool                           `json:"is_default"  binding:"required"`
+	ParentID     *string                        `json:"parent_id,omitempty"`
+	RemovedKeys  []string                       `json:"removed_keys,omitempty"`
+}
+
+// PresentationSpecSummary is a spec without its content, as listed to admins
+type PresentationSpecSummary struct {
+	ID          string    `json:"id"`
+	Version     int       `json:"version"`
+	Base        string    `json:"base"`
+	UserEmail   string    `json:"user_email"`
+	UserCompany string    `json:"user_company"`
+	Service     string    `json:"service"`
+	IsDefault   bool      `json:"is_default"`
+	ParentID    *string   `json:"parent_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 func (ps *PresentationSpec) GetOrderedSheetOptions() []PresentationSpecSheetOptions {
@@ -1,6 +1,9 @@
 package ports
 
-import "fmt"
+import (
+	"export-service/internal/core/domain"
+	"fmt"
+)
 
 type RFC7807Error struct {
 	Type   string `json:"type"`
@@ -22,6 +25,11 @@ type InvalidBodyError struct {
 	//Aditional fields
 }
 
+type InvalidSpecError struct {
+	RFC7807Error
+	Issues []domain.PresentationSpecIssue `json:"issues"`
+}
+
 func NewInvalidBodyError() InvalidBodyError {
 	return InvalidBodyError{
 		RFC7807Error: RFC7807Error{
@@ -41,3 +49,14 @@ func NewInvalidQueryParamsError() InvalidQueryParamsError {
 		},
 	}
 }
+
+func NewInvalidSpecError(issues []domain.PresentationSpecIssue) InvalidSpecError {
+	return InvalidSpecError{
+		RFC7807Error: RFC7807Error{
+			Type:   "InvalidSpec",
+			Title:  "Invalid Presentation Spec",
+			Detail: fmt.Sprintf("Presentation spec has %d issues.", len(issues)),
+		},
+		Issues: issues,
+	}
+}
@@ -3,6 +3,8 @@ package ports
 import (
 	"context"
 	"export-service/internal/core/domain"
+	"io"
+	"time"
 )
 
 type CrmCompanyQueryParams struct {
@@ -21,6 +23,28 @@ type CrmAddHubspotCompanyQueryParams struct {
 	RefreshToken string
 	AccessToken  string
 	ExpiresIn    string
+	InstanceUrl  string
+}
+
+// CrmAddCompanyQueryParams holds the credentials saved when a crm is installed, each crm uses only some of them
+type CrmAddCompanyQueryParams struct {
+	Crm          string
+	WorkspaceId  string
+	UserId       string
+	Token        string
+	RefreshToken string
+	AccessToken  string
+	ExpiresIn    string
+	InstanceUrl  string
+	Webhook      string
+}
+
+type CrmUpdateTokensQueryParams struct {
+	Id           string
+	RefreshToken string
+	AccessToken  string
+	ExpiresIn    string
+	InstanceUrl  string
 }
 
 type PresentationSpecQueryParams struct {
@@ -30,16 +54,80 @@ type PresentationSpecQueryParams struct {
 	DataSource  string
 }
 
+// PresentationSpecListParams filters the listing, empty fields are ignored. Search matches the user email or company
+type PresentationSpecListParams struct {
+	UserCompany   string
+	Service       string
+	DataSource    string
+	Search        string
+	IsDefault     *bool
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Page          int
+	PageSize      int
+}
+
+// PresentationSpecList is a page of specs, Total and CountByService consider every spec that matches the filters
+type PresentationSpecList struct {
+	Items          []domain.PresentationSpecSummary `json:"items"`
+	Page           int                              `json:"page"`
+	PageSize       int                              `json:"page_size"`
+	Total          int                              `json:"total"`
+	CountByService map[string]int                   `json:"count_by_service"`
+}
+
 type PresentationSpecAddBody struct {
 	PresentationSpec domain.PresentationSpecSpec           `json:"spec" validate:"required"`
 	SpecOptions      []domain.PresentationSpecSheetOptions `json:"sheet_options" validate:"required"`
 }
 
+// PresentationSpecDocument is a spec as a portable json document, in the same shape as mapping.json.
+// service and base are used by the import when they are not in the query params, the other origin fields are informative
+type PresentationSpecDocument struct {
+	Service          string                                `json:"service,omitempty"`
+	Base             string                                `json:"base,omitempty"`
+	SourceID         string                                `json:"source_id,omitempty"`
+	SourceVersion    int                                   `json:"source_version,omitempty"`
+	ExportedAt       *time.Time                            `json:"exported_at,omitempty"`
+	PresentationSpec domain.PresentationSpecSpec           `json:"spec" validate:"required"`
+	SpecOptions      []domain.PresentationSpecSheetOptions `json:"sheet_options" validate:"required"`
+}
+
+// what the import does when the user already has a spec for the service and base
+const (
+	ImportConflictFail    = "fail"
+	ImportConflictReplace = "replace"
+	ImportConflictMerge   = "merge"
+)
+
 type PresentationSpecPatchKey struct {
 	PresentationSpec map[string]any                           `json:"spec" validate:"required"`
 	SpecOptions      domain.PresentationSpecPatchSheetOptions `json:"sheet_options" validate:"required"`
 }
 
+type PresentationSpecValidation struct {
+	Valid  bool                           `json:"valid"`
+	Issues []domain.PresentationSpecIssue `json:"issues"`
+}
+
+type PresentationSpecPreviewBody struct {
+	PresentationSpec domain.PresentationSpecSpec           `json:"spec"`
+	SpecOptions      []domain.PresentationSpecSheetOptions `json:"sheet_options"`
+	Records          []map[string]any                      `json:"records"`
+}
+
+// SheetPreview is a sheet as the spreadsheet writers would write it, with the header labels and typed cells
+type SheetPreview struct {
+	Key     string   `json:"key"`
+	Headers []string `json:"headers"`
+	Rows    [][]any  `json:"rows"`
+}
+
+type PresentationSpecPreview struct {
+	Presented []map[string]any `json:"presented"`
+	Sheets    []SheetPreview   `json:"sheets"`
+}
+
 type PresentationSpecPatchSource struct {
 	DealSource     string `json:"deal_source,omitempty"`
 	CompanySource  string `json:"company_source,omitempty"`
@@ -50,21 +138,48 @@ type PresentationSpecPatchSource struct {
 
 type PresentationSpecRepository interface {
 	Get(ctx context.Context, params PresentationSpecQueryParams) (domain.PresentationSpec, error)
-	Add(ctx context.Context, params PresentationSpecQueryParams, body PresentationSpecAddBody) (domain.PresentationSpec, error)
+	Add(ctx context.Context, params PresentationSpecQueryParams, body PresentationSpecAddBody, author string) (domain.PresentationSpec, error)
+	List(ctx context.Context, params PresentationSpecListParams) (PresentationSpecList, error)
 }
 
 type DataWriter interface {
-	Write(data []map[string]any, spec domain.PresentationSpec) (string, error)
+	Open(spec domain.PresentationSpec) (RowWriter, error)
+}
+
+// RowWriter writes presented rows, Close returns the paths of every file written, usually just one.
+// Abort discards a failed write, closing the open files and removing everything written
+type RowWriter interface {
+	WriteRow(row map[string]any) error
+	Close() ([]string, error)
+	Abort() error
+}
+
+// ExportSummary describes an export for the writers that can add it to the file
+type ExportSummary struct {
+	ListName                string
+	DataSource              string
+	UserName                string
+	UserEmail               string
+	PresentationSpecID      string
+	PresentationSpecVersion int
+	ExportedAt              time.Time
+}
+
+// SummaryRowWriter is implemented by the row writers that add a sheet describing the export to each file
+type SummaryRowWriter interface {
+	RowWriter
+	SetSummary(summary ExportSummary)
 }
 
 type Downloader interface {
-	Download(url string) ([]byte, error)
+	Download(url string) (io.ReadCloser, error)
 }
 
 type Uploader interface {
 	Upload(fileName, path string) (string, error)
 }
 
+// Mailer sends the download links of an export, exports split in parts send every part in the same email
 type Mailer interface {
-	SendEmail(userEmail, userName, templateId, link string) error
+	SendEmail(userEmail, userName, templateId string, links []string) error
 }
@@ -14,11 +14,15 @@ import (
 )
 
 func InstallHandler(c *fiber.Ctx, crmService crm_exporter.Crm) error {
-	//hubspot doesnt require install data like a token (its oauth), other CRMs may require
-	installData := map[string]any{
-		"workspace_id": c.Query("workspace_id"),
-		"user_id":      c.Query("user_id"),
+	//hubspot doesnt require install data like a token (its oauth), other CRMs may require it in the body
+	installData := map[string]any{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&installData); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+		}
 	}
+	installData["workspace_id"] = c.Query("workspace_id")
+	installData["user_id"] = c.Query("user_id")
 	response, err := crmService.Install(installData)
 
 	status := fiber.StatusOK
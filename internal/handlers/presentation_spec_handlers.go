@@ -2,14 +2,30 @@ package handlers
 
 import (
 	"errors"
+	"export-service/internal/core/domain"
 	"export-service/internal/core/ports"
+	"export-service/internal/gateways"
 	"export-service/internal/repositories"
 	"export-service/internal/repositories/presentation_spec_repo"
+	"export-service/internal/services/crm_exporter"
+	"export-service/internal/services/data_presenter"
+	"export-service/internal/writers"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
 
+// getAuthor returns the email of the user set by the auth middleware, recorded in the spec history
+func getAuthor(c *fiber.Ctx) string {
+	user, ok := c.Locals("user").(gateways.AuthUser)
+	if !ok {
+		return ""
+	}
+	return user.Email
+}
+
 func GetPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
 	companyName := c.Query("user_company")
 	userEmail := c.Query("user_email")
@@ -47,6 +63,75 @@ func GetPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresen
 	return c.Status(status).JSON(returnBody)
 }
 
+// ListPresentationSpecsHandler lists specs filtered by user_company, service, base, search, is_default,
+// updated_after and updated_before, paginated by page and page_size
+func ListPresentationSpecsHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	params, err := getListParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidQueryParamsError())
+	}
+
+	list, err := p.List(c.Context(), params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(list)
+}
+
+func getListParams(c *fiber.Ctx) (ports.PresentationSpecListParams, error) {
+	params := ports.PresentationSpecListParams{
+		UserCompany: c.Query("user_company"),
+		Service:     c.Query("service"),
+		DataSource:  c.Query("base"),
+		Search:      c.Query("search"),
+	}
+
+	var err error
+	if page := c.Query("page"); page != "" {
+		if params.Page, err = strconv.Atoi(page); err != nil {
+			return params, err
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if params.PageSize, err = strconv.Atoi(pageSize); err != nil {
+			return params, err
+		}
+	}
+
+	if isDefault := c.Query("is_default"); isDefault != "" {
+		boolDefault, err := strconv.ParseBool(isDefault)
+		if err != nil {
+			return params, err
+		}
+		params.IsDefault = &boolDefault
+	}
+
+	if params.UpdatedAfter, err = parseQueryDate(c.Query("updated_after")); err != nil {
+		return params, err
+	}
+	if params.UpdatedBefore, err = parseQueryDate(c.Query("updated_before")); err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
+
+// parseQueryDate accepts RFC3339 timestamps or dates, which are read as midnight UTC
+func parseQueryDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if date, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, err
+		}
+	}
+	return &date, nil
+}
+
 func AddPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
 	companyName := c.Query("user_company")
 	userEmail := c.Query("user_email")
@@ -64,12 +149,16 @@ func AddPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresen
 		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
 	}
 
+	if issues := data_presenter.ValidateSpec(body.PresentationSpec, body.SpecOptions); len(issues) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidSpecError(issues))
+	}
+
 	addedSpec, err := p.Add(c.Context(), ports.PresentationSpecQueryParams{
 		UserEmail:   userEmail,
 		UserCompany: companyName,
 		Service:     service,
 		DataSource:  base,
-	}, body)
+	}, body, getAuthor(c))
 
 	status := fiber.StatusOK
 	var returnBody any
@@ -92,6 +181,158 @@ func AddPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresen
 	return c.Status(status).JSON(returnBody)
 }
 
+func ValidatePresentationSpecHandler(c *fiber.Ctx) error {
+	var body ports.PresentationSpecAddBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+	}
+
+	validate := validator.New()
+	if invalidStruct := validate.Struct(body); invalidStruct != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+	}
+
+	issues := data_presenter.ValidateSpec(body.PresentationSpec, body.SpecOptions)
+	if issues == nil {
+		issues = []domain.PresentationSpecIssue{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(ports.PresentationSpecValidation{Valid: len(issues) == 0, Issues: issues})
+}
+
+const maxPreviewRecords = 20
+
+// PreviewPresentationSpecHandler presents sample records with the stored spec of :id, or with the spec in the body
+// when there is no id. without records in the body the driva test lead is used, and only the first records are shown
+func PreviewPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	var body ports.PresentationSpecPreviewBody
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+		}
+	}
+
+	spec := domain.PresentationSpec{Spec: body.PresentationSpec, SheetOptions: body.SpecOptions}
+	if id := c.Params("id"); id != "" {
+		storedSpec, err := p.GetById(c.Context(), id)
+		if err != nil {
+			var notFoundErr repositories.PresentationSpecNotFoundError
+			if errors.As(err, &notFoundErr) {
+				return c.Status(fiber.StatusNotFound).JSON(err)
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(err)
+		}
+		spec = storedSpec
+	} else {
+		if body.PresentationSpec == nil || body.SpecOptions == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+		}
+		if issues := data_presenter.ValidateSpec(body.PresentationSpec, body.SpecOptions); len(issues) > 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidSpecError(issues))
+		}
+	}
+
+	records := body.Records
+	if len(records) == 0 {
+		records = []map[string]any{crm_exporter.DrivaTestLead}
+	}
+	if len(records) > maxPreviewRecords {
+		records = records[:maxPreviewRecords]
+	}
+
+	presented := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		result, err := data_presenter.PresentSingle(record, spec.Spec)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		presented = append(presented, result)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(ports.PresentationSpecPreview{
+		Presented: presented,
+		Sheets:    writers.PreviewSheets(presented, spec),
+	})
+}
+
+// ExportPresentationSpecHandler returns the effective spec of :id as a document that can be imported elsewhere
+func ExportPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	spec, err := p.GetById(c.Context(), c.Params("id"))
+	if err != nil {
+		var notFoundErr repositories.PresentationSpecNotFoundError
+		if errors.As(err, &notFoundErr) {
+			return c.Status(fiber.StatusNotFound).JSON(err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(err)
+	}
+
+	exportedAt := time.Now().UTC()
+	c.Attachment(spec.Service + "-" + spec.Base + ".json")
+	return c.Status(fiber.StatusOK).JSON(ports.PresentationSpecDocument{
+		Service:          spec.Service,
+		Base:             spec.Base,
+		SourceID:         spec.ID,
+		SourceVersion:    spec.Version,
+		ExportedAt:       &exportedAt,
+		PresentationSpec: spec.Spec,
+		SpecOptions:      spec.GetOrderedSheetOptions(),
+	})
+}
+
+// ImportPresentationSpecHandler imports a spec document for the user in the query params. on_conflict is fail,
+// replace or merge and defaults to fail. service and base fall back to the ones in the document
+func ImportPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	var document ports.PresentationSpecDocument
+	if err := c.BodyParser(&document); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+	}
+
+	validate := validator.New()
+	if invalidStruct := validate.Struct(document); invalidStruct != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
+	}
+
+	if issues := data_presenter.ValidateSpec(document.PresentationSpec, document.SpecOptions); len(issues) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidSpecError(issues))
+	}
+
+	onConflict := c.Query("on_conflict", ports.ImportConflictFail)
+	switch onConflict {
+	case ports.ImportConflictFail, ports.ImportConflictReplace, ports.ImportConflictMerge:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidQueryParamsError())
+	}
+
+	importedSpec, err := p.Import(c.Context(), ports.PresentationSpecQueryParams{
+		UserEmail:   c.Query("user_email"),
+		UserCompany: c.Query("user_company"),
+		Service:     c.Query("service", document.Service),
+		DataSource:  c.Query("base", document.Base),
+	}, ports.PresentationSpecAddBody{PresentationSpec: document.PresentationSpec, SpecOptions: document.SpecOptions}, onConflict, getAuthor(c))
+
+	status := fiber.StatusOK
+	var returnBody any
+	returnBody = importedSpec
+	if err != nil {
+		returnBody = err
+		var conflictErr repositories.PresentationSpecConflictError
+		var invalidQueryParams ports.InvalidQueryParamsError
+
+		switch {
+		case errors.As(err, &conflictErr):
+			status = fiber.StatusConflict
+		case errors.As(err, &invalidQueryParams):
+			status = fiber.StatusBadRequest
+		default:
+			status = fiber.StatusInternalServerError
+		}
+	}
+
+	return c.Status(status).JSON(returnBody)
+}
+
 func PatchPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
 	id := c.Params("id")
 
@@ -105,7 +346,11 @@ func PatchPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPres
 		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
 	}
 
-	updatedSpec, err := p.Patch(c.Context(), id, body)
+	if issues := data_presenter.ValidateSpec(body.PresentationSpec, body.SpecOptions); len(issues) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidSpecError(issues))
+	}
+
+	updatedSpec, err := p.Patch(c.Context(), id, body, getAuthor(c))
 	status := fiber.StatusOK
 	var returnBody any
 	returnBody = updatedSpec
@@ -153,7 +398,8 @@ func PatchSourceHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSp
     }
 
 	if (existingSpec.IsDefault) {
-		newSpec, err := p.Add(c.Context(), ports.PresentationSpecQueryParams{UserEmail: userEmail, UserCompany: companyName, Service: service, DataSource: base}, ports.PresentationSpecAddBody{PresentationSpec: existingSpec.Spec, SpecOptions: existingSpec.SheetOptions})
+		// a cópia herda do default e guarda só as alterações
+		newSpec, err := p.Inherit(c.Context(), ports.PresentationSpecQueryParams{UserEmail: userEmail, UserCompany: companyName, Service: service, DataSource: base}, existingSpec.ID, getAuthor(c))
 		if err!= nil {
             return c.Status(fiber.StatusInternalServerError).JSON(err)
         }
@@ -162,7 +408,7 @@ func PatchSourceHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSp
 		specId = existingSpec.ID
 	}
 
-	updatedSpec, err := p.PatchSource(c.Context(), specId, body)
+	updatedSpec, err := p.PatchSource(c.Context(), specId, body, getAuthor(c))
 	status := fiber.StatusOK
 	var returnBody any
 	returnBody = updatedSpec
@@ -197,7 +443,13 @@ func PatchPresentationSpecKeyHandler(c *fiber.Ctx, p *presentation_spec_repo.PgP
 		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidBodyError())
 	}
 
-	updatedSpec, err := p.PatchKey(c.Context(), id, key, body)
+	keySpec := domain.PresentationSpecSpec{body.SpecOptions.Key: body.PresentationSpec}
+	keyOptions := []domain.PresentationSpecSheetOptions{{Key: body.SpecOptions.Key}}
+	if issues := data_presenter.ValidateSpec(keySpec, keyOptions); len(issues) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidSpecError(issues))
+	}
+
+	updatedSpec, err := p.PatchKey(c.Context(), id, key, body, getAuthor(c))
 	status := fiber.StatusOK
 	var returnBody any
 	returnBody = updatedSpec
@@ -226,10 +478,13 @@ func DeletePresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPre
 	status := fiber.StatusNoContent
 	if err != nil {
 		var invalidQueryParams ports.InvalidQueryParamsError
+		var hasChildren repositories.PresentationSpecHasChildrenError
 
 		switch {
 		case errors.As(err, &invalidQueryParams):
 			status = fiber.StatusBadRequest
+		case errors.As(err, &hasChildren):
+			status = fiber.StatusConflict
 		default:
 			status = fiber.StatusInternalServerError
 		}
@@ -239,3 +494,67 @@ func DeletePresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPre
 
 	return c.SendStatus(status)
 }
+
+func GetPresentationSpecVersionsHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	versions, err := p.GetVersions(c.Context(), c.Params("id"))
+	if err != nil {
+		var invalidQueryParams ports.InvalidQueryParamsError
+		if errors.As(err, &invalidQueryParams) {
+			return c.Status(fiber.StatusBadRequest).JSON(err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(versions)
+}
+
+// DiffPresentationSpecVersionsHandler compares the versions in the from and to query params
+func DiffPresentationSpecVersionsHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	id := c.Params("id")
+
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidQueryParamsError())
+	}
+
+	fromVersion, err := p.GetVersion(c.Context(), id, from)
+	if err != nil {
+		return c.Status(getVersionErrorStatus(err)).JSON(err)
+	}
+
+	toVersion, err := p.GetVersion(c.Context(), id, to)
+	if err != nil {
+		return c.Status(getVersionErrorStatus(err)).JSON(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(data_presenter.DiffSpecVersions(fromVersion, toVersion))
+}
+
+func RollbackPresentationSpecHandler(c *fiber.Ctx, p *presentation_spec_repo.PgPresentationSpecRepository) error {
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ports.NewInvalidQueryParamsError())
+	}
+
+	updatedSpec, err := p.Rollback(c.Context(), c.Params("id"), version, getAuthor(c))
+	if err != nil {
+		return c.Status(getVersionErrorStatus(err)).JSON(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updatedSpec)
+}
+
+func getVersionErrorStatus(err error) int {
+	var notFoundErr repositories.PresentationSpecVersionNotFoundError
+	var invalidQueryParams ports.InvalidQueryParamsError
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		return fiber.StatusNotFound
+	case errors.As(err, &invalidQueryParams):
+		return fiber.StatusBadRequest
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
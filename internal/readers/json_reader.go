@@ -0,0 +1,88 @@
+package readers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"unicode"
+)
+
+// JSONRecordReader decodes one record at a time from a JSON array of objects
+// or from a JSONL stream, so the whole list never needs to be held in memory.
+type JSONRecordReader struct {
+	source  io.ReadCloser
+	reader  *bufio.Reader
+	decoder *json.Decoder
+	isArray bool
+	started bool
+}
+
+func NewJSONRecordReader(source io.ReadCloser) *JSONRecordReader {
+	reader := bufio.NewReader(source)
+	return &JSONRecordReader{
+		source:  source,
+		reader:  reader,
+		decoder: json.NewDecoder(reader),
+	}
+}
+
+// Next returns the next record, or io.EOF once the input is exhausted.
+func (j *JSONRecordReader) Next() (map[string]any, error) {
+	if !j.started {
+		if err := j.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if j.isArray && !j.decoder.More() {
+		if _, err := j.decoder.Token(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	var record map[string]any
+	if err := j.decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (j *JSONRecordReader) Close() error {
+	return j.source.Close()
+}
+
+func (j *JSONRecordReader) start() error {
+	j.started = true
+
+	first, err := j.peekFirstRune()
+	if err != nil {
+		return err
+	}
+
+	switch first {
+	case '[':
+		j.isArray = true
+		_, err := j.decoder.Token()
+		return err
+	case '{':
+		return nil
+	default:
+		return errors.New("data must be a JSON array of objects or JSONL")
+	}
+}
+
+func (j *JSONRecordReader) peekFirstRune() (rune, error) {
+	for {
+		r, _, err := j.reader.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+
+		if !unicode.IsSpace(r) && r != '\uFEFF' {
+			return r, j.reader.UnreadRune()
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package readers
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, input string) ([]map[string]any, error) {
+	t.Helper()
+	reader := NewJSONRecordReader(io.NopCloser(strings.NewReader(input)))
+	defer reader.Close()
+
+	var records []map[string]any
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestJSONRecordReader_Next(t *testing.T) {
+	t.Run("Should read a JSON array", func(t *testing.T) {
+		records, err := readAll(t, ` [{"cnpj": 1, "name": "a"}, {"cnpj": 2, "name": "b"}] `)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, float64(1), records[0]["cnpj"])
+		assert.Equal(t, "b", records[1]["name"])
+	})
+
+	t.Run("Should read JSONL", func(t *testing.T) {
+		records, err := readAll(t, "{\"cnpj\": 1}\n{\"cnpj\": 2}\n{\"cnpj\": 3}\n")
+		require.NoError(t, err)
+		assert.Len(t, records, 3)
+	})
+
+	t.Run("Should read an empty array", func(t *testing.T) {
+		records, err := readAll(t, "[]")
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("Should fail if value is not an array of objects", func(t *testing.T) {
+		_, err := readAll(t, "<!DOCTYPE html><html><body></body></html>")
+		assert.Error(t, err)
+
+		_, err = readAll(t, "[1, 2]")
+		assert.Error(t, err)
+	})
+
+	t.Run("Should fail on truncated input", func(t *testing.T) {
+		records, err := readAll(t, `[{"cnpj": 1}, {"cnpj":`)
+		assert.Error(t, err)
+		assert.Len(t, records, 1)
+	})
+}
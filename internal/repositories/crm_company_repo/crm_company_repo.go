@@ -6,6 +6,7 @@ import (
 	"export-service/internal/core/ports"
 	"export-service/internal/repositories"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
@@ -100,6 +101,46 @@ func (r *PgCrmCompanyRepository) AddHubspot(ctx context.Context, params ports.Cr
 	return company, nil
 }
 
+// AddCompany saves the installation of any crm, credentials left empty are saved as null
+func (r *PgCrmCompanyRepository) AddCompany(ctx context.Context, params ports.CrmAddCompanyQueryParams) (Company, error) {
+	defer r.logger.Sync()
+
+	if params.Crm == "" || params.WorkspaceId == "" || params.UserId == "" {
+		return Company{}, ports.NewInvalidQueryParamsError()
+	}
+
+	rows, err := r.conn.Query(ctx, addCompanyQuery, uuid.New().String(), params.Crm, params.UserId, params.WorkspaceId, params.Token, params.RefreshToken, params.AccessToken, params.ExpiresIn, params.InstanceUrl, params.Webhook)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("crm", params.Crm), zap.String("workspace_id", params.WorkspaceId), zap.Error(err))
+		return Company{}, err
+	}
+	defer rows.Close()
+
+	company, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Company])
+	if err != nil {
+		r.logger.Error("Got error when collecting one row", zap.String("crm", params.Crm), zap.String("workspace_id", params.WorkspaceId), zap.Error(err))
+		return Company{}, err
+	}
+
+	return company, nil
+}
+
+// UpdateTokens saves refreshed oauth tokens, the refresh token is kept when the crm does not send a new one
+func (r *PgCrmCompanyRepository) UpdateTokens(ctx context.Context, params ports.CrmUpdateTokensQueryParams) error {
+	defer r.logger.Sync()
+
+	if params.Id == "" || params.AccessToken == "" {
+		return ports.NewInvalidQueryParamsError()
+	}
+
+	if _, err := r.conn.Exec(ctx, updateTokensQuery, params.Id, params.RefreshToken, params.AccessToken, params.ExpiresIn, params.InstanceUrl); err != nil {
+		r.logger.Error("Failed to execute query", zap.String("id", params.Id), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 // func (r *PgPresentationSpecRepository) GetById(ctx context.Context, id string) (domain.PresentationSpec, error) {
 // 	defer r.logger.Sync()
 
@@ -11,3 +11,12 @@ const getByCompanyNameQuery = `
 const addHubspotQuery = `
 	insert into crm.company (crm, user_who_installed_id, workspace_id, refresh_token, access_token, created_at, updated_at, expires_in) values ('hubspot', $1, $2, $3, $4, now(), now(), $5) returning *;
 `
+
+const addCompanyQuery = `
+	insert into crm.company (id, crm, user_who_installed_id, workspace_id, token, refresh_token, access_token, expires_in, instance_url, webhook, created_at, updated_at)
+	values ($1, $2, $3, $4, nullif($5, ''), nullif($6, ''), nullif($7, ''), nullif($8, ''), nullif($9, ''), nullif($10, ''), now(), now()) returning *;
+`
+
+const updateTokensQuery = `
+	update crm.company set refresh_token = coalesce(nullif($2, ''), refresh_token), access_token = $3, expires_in = nullif($4, ''), instance_url = coalesce(nullif($5, ''), instance_url), refreshed_at = now()::text, updated_at = now() where id = $1;
+`
@@ -96,6 +96,25 @@ func (r *PgCrmSolicitationRepository) UpdateStatus(ctx context.Context, newStatu
 	return solicitation, nil
 }
 
+// UpdatePresentationSpec records the spec version used by the export, a resumed export records the version it resumed with
+func (r *PgCrmSolicitationRepository) UpdatePresentationSpec(ctx context.Context, specId string, specVersion int, listId, crm string) (Solicitation, error) {
+	defer r.logger.Sync()
+
+	rows, _ := r.conn.Query(ctx, updatePresentationSpecQuery, specId, specVersion, listId, crm)
+
+	solicitation, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[Solicitation])
+	if err != nil {
+		r.logger.Error("Got error when collecting one row", zap.Error(err), zap.Any("params", map[string]any{"specId": specId, "specVersion": specVersion}))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Solicitation{}, repositories.NewSolicitationNotFoundError()
+		}
+
+		return Solicitation{}, err
+	}
+
+	return solicitation, nil
+}
+
 func (r *PgCrmSolicitationRepository) IncrementCurrent(ctx context.Context, listId, crm string) (Solicitation, error) {
 	defer r.logger.Sync()
 
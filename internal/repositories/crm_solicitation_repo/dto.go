@@ -1,6 +1,7 @@
 package crm_solicitation_repo
 
 import (
+	"database/sql"
 	"export-service/internal/services/crm_exporter"
 	"time"
 )
@@ -33,6 +34,10 @@ type Solicitation struct {
 	Current       int
 	Total         int
 
+	// versão do presentation spec usada no envio, nula nas solicitações anteriores ao histórico de versões
+	PresentationSpecId      sql.NullString
+	PresentationSpecVersion sql.NullInt32
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -12,6 +12,11 @@ const incrementCurrentQuery = `
 	update crm.solicitation_v2 set current = current + 1 where list_id = $1 and crm = $2 returning *
 `
 
+// presentation_spec_id (text) e presentation_spec_version (int) são colunas nulas de crm.solicitation_v2
+const updatePresentationSpecQuery = `
+	update crm.solicitation_v2 set presentation_spec_id = $1, presentation_spec_version = $2 where list_id = $3 and crm = $4 returning *
+`
+
 const updateExportedCompanies = `
 	UPDATE crm.solicitation_v2
 	SET exported_companies = jsonb_set(
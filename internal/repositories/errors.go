@@ -34,11 +34,26 @@ type PresentationSpecNotFoundError struct {
 	//Aditional fields
 }
 
+type PresentationSpecVersionNotFoundError struct {
+	RFC7807Error
+	//Aditional fields
+}
+
 type PresentationSpecNotUniqueError struct {
 	RFC7807Error
 	//Aditional fields
 }
 
+type PresentationSpecConflictError struct {
+	RFC7807Error
+	//Aditional fields
+}
+
+type PresentationSpecHasChildrenError struct {
+	RFC7807Error
+	//Aditional fields
+}
+
 type InvalidJsonBodyError struct {
 	RFC7807Error
 	//Aditional fields
@@ -106,6 +121,16 @@ func NewPresentationSpecNotFoundError() PresentationSpecNotFoundError {
 	}
 }
 
+func NewPresentationSpecVersionNotFoundError() PresentationSpecVersionNotFoundError {
+	return PresentationSpecVersionNotFoundError{
+		RFC7807Error: RFC7807Error{
+			Type:   "PresentationSpecVersionNotFoundError",
+			Title:  "Presentation Spec Version Not Found",
+			Detail: "The requested presentation specification version could not be found.",
+		},
+	}
+}
+
 func NewPresentationSpecNotUniqueError() PresentationSpecNotUniqueError {
 	return PresentationSpecNotUniqueError{
 		RFC7807Error: RFC7807Error{
@@ -116,6 +141,26 @@ func NewPresentationSpecNotUniqueError() PresentationSpecNotUniqueError {
 	}
 }
 
+func NewPresentationSpecConflictError() PresentationSpecConflictError {
+	return PresentationSpecConflictError{
+		RFC7807Error: RFC7807Error{
+			Type:   "PresentationSpecConflictError",
+			Title:  "Presentation Spec Conflict",
+			Detail: "A presentation specification already exists for given parameters.",
+		},
+	}
+}
+
+func NewPresentationSpecHasChildrenError() PresentationSpecHasChildrenError {
+	return PresentationSpecHasChildrenError{
+		RFC7807Error: RFC7807Error{
+			Type:   "PresentationSpecHasChildrenError",
+			Title:  "Presentation Spec Has Children",
+			Detail: "Other presentation specifications inherit from this one, delete them first.",
+		},
+	}
+}
+
 func NewInvalidJsonBodyError() InvalidJsonBodyError {
 	return InvalidJsonBodyError{
 		RFC7807Error: RFC7807Error{
@@ -0,0 +1,103 @@
+package presentation_spec_repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"sort"
+)
+
+// specs that inherit from a default only store the keys that differ from the parent and the parent keys removed.
+// the effective spec is resolved when read, so later changes to the default reach every child
+
+// mergeSpec applies the overrides of a child on top of the effective spec of its parent
+func mergeSpec(parent domain.PresentationSpec, spec domain.PresentationSpecSpec, sheetOptions []domain.PresentationSpecSheetOptions, removedKeys []string) (domain.PresentationSpecSpec, []domain.PresentationSpecSheetOptions) {
+	removed := make(map[string]bool, len(removedKeys))
+	for _, key := range removedKeys {
+		removed[key] = true
+	}
+
+	mergedSpec := make(domain.PresentationSpecSpec, len(parent.Spec)+len(spec))
+	for key, value := range parent.Spec {
+		if !removed[key] {
+			mergedSpec[key] = value
+		}
+	}
+	for key, value := range spec {
+		mergedSpec[key] = value
+	}
+
+	optionsByKey := make(map[string]domain.PresentationSpecSheetOptions, len(parent.SheetOptions)+len(sheetOptions))
+	for _, options := range parent.SheetOptions {
+		if !removed[options.Key] {
+			optionsByKey[options.Key] = options
+		}
+	}
+	for _, options := range sheetOptions {
+		optionsByKey[options.Key] = options
+	}
+
+	mergedOptions := make([]domain.PresentationSpecSheetOptions, 0, len(optionsByKey))
+	for _, options := range optionsByKey {
+		mergedOptions = append(mergedOptions, options)
+	}
+	sort.Slice(mergedOptions, func(i, j int) bool {
+		if mergedOptions[i].Position == mergedOptions[j].Position {
+			return mergedOptions[i].Key < mergedOptions[j].Key
+		}
+		return mergedOptions[i].Position < mergedOptions[j].Position
+	})
+
+	return mergedSpec, mergedOptions
+}
+
+// getOverrides is the opposite of mergeSpec, it keeps only what the body changes in the effective spec of the parent.
+// without a parent the whole body is stored
+func getOverrides(parent *domain.PresentationSpec, body ports.PresentationSpecAddBody) (ports.PresentationSpecAddBody, []string) {
+	if parent == nil {
+		return body, nil
+	}
+
+	overrides := ports.PresentationSpecAddBody{PresentationSpec: domain.PresentationSpecSpec{}}
+	for key, value := range body.PresentationSpec {
+		parentValue, exists := parent.Spec[key]
+		if !exists || !sameJSON(parentValue, value) {
+			overrides.PresentationSpec[key] = value
+		}
+	}
+
+	parentOptions := make(map[string]domain.PresentationSpecSheetOptions, len(parent.SheetOptions))
+	for _, options := range parent.SheetOptions {
+		parentOptions[options.Key] = options
+	}
+	for _, options := range body.SpecOptions {
+		previous, exists := parentOptions[options.Key]
+		if !exists || !sameJSON(previous, options) {
+			overrides.SpecOptions = append(overrides.SpecOptions, options)
+		}
+	}
+
+	var removedKeys []string
+	for key := range parent.Spec {
+		if _, exists := body.PresentationSpec[key]; !exists {
+			removedKeys = append(removedKeys, key)
+		}
+	}
+	sort.Strings(removedKeys)
+
+	return overrides, removedKeys
+}
+
+// sameJSON compares values read from the database with values from requests, which only match as json
+func sameJSON(a, b any) bool {
+	aJSON, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bJSON, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aJSON, bJSON)
+}
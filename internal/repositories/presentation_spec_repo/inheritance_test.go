@@ -0,0 +1,72 @@
+package presentation_spec_repo
+
+import (
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInheritanceOverrides(t *testing.T) {
+	parent := domain.PresentationSpec{
+		Spec: domain.PresentationSpecSpec{
+			"RFB":       {"CNPJ": "cnpj", "Nome": "razao_social"},
+			"Telefones": {"Telefone": "telefone"},
+		},
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ", "Nome"}, Position: 0},
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 1},
+		},
+	}
+
+	body := ports.PresentationSpecAddBody{
+		PresentationSpec: domain.PresentationSpecSpec{
+			"RFB":    {"CNPJ": "cnpj", "Nome": "razao_social"},
+			"Emails": {"Email": "email"},
+		},
+		SpecOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 0},
+			{Key: "Emails", ActiveColumns: []string{"Email"}, Position: 1},
+		},
+	}
+
+	t.Run("Should keep only what differs from the parent", func(t *testing.T) {
+		overrides, removedKeys := getOverrides(&parent, body)
+
+		require.Equal(t, domain.PresentationSpecSpec{"Emails": {"Email": "email"}}, overrides.PresentationSpec)
+		require.Equal(t, body.SpecOptions, overrides.SpecOptions)
+		require.Equal(t, []string{"Telefones"}, removedKeys)
+	})
+
+	t.Run("Should resolve the overrides back to the body", func(t *testing.T) {
+		overrides, removedKeys := getOverrides(&parent, body)
+		spec, sheetOptions := mergeSpec(parent, overrides.PresentationSpec, overrides.SpecOptions, removedKeys)
+
+		require.Equal(t, body.PresentationSpec, spec)
+		require.Equal(t, body.SpecOptions, sheetOptions)
+	})
+
+	t.Run("Should follow changes to the parent", func(t *testing.T) {
+		overrides, removedKeys := getOverrides(&parent, body)
+
+		changedParent := parent
+		changedParent.Spec = domain.PresentationSpecSpec{
+			"RFB":       {"CNPJ": "cnpj", "Nome": "razao_social", "Site": "site"},
+			"Telefones": {"Telefone": "telefone"},
+		}
+		spec, _ := mergeSpec(changedParent, overrides.PresentationSpec, overrides.SpecOptions, removedKeys)
+
+		require.Equal(t, domain.PresentationSpecSpec{
+			"RFB":    {"CNPJ": "cnpj", "Nome": "razao_social", "Site": "site"},
+			"Emails": {"Email": "email"},
+		}, spec)
+	})
+
+	t.Run("Should store the whole body without a parent", func(t *testing.T) {
+		overrides, removedKeys := getOverrides(nil, body)
+
+		require.Equal(t, body, overrides)
+		require.Nil(t, removedKeys)
+	})
+}
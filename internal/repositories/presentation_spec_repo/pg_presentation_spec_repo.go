@@ -50,7 +50,7 @@ func (r *PgPresentationSpecRepository) Get(ctx context.Context, params ports.Pre
 
 		return domain.PresentationSpec{}, err
 	}
-	return spec, nil
+	return r.resolve(ctx, spec)
 }
 
 func (r *PgPresentationSpecRepository) GetById(ctx context.Context, id string) (domain.PresentationSpec, error) {
@@ -74,9 +74,107 @@ func (r *PgPresentationSpecRepository) GetById(ctx context.Context, id string) (
 
 		return domain.PresentationSpec{}, err
 	}
+	return r.resolve(ctx, spec)
+}
+
+// resolve merges the overrides of an inherited spec with its parent, specs without a parent are returned as they are
+func (r *PgPresentationSpecRepository) resolve(ctx context.Context, spec domain.PresentationSpec) (domain.PresentationSpec, error) {
+	if spec.ParentID == nil {
+		return spec, nil
+	}
+
+	parent, err := r.GetById(ctx, *spec.ParentID)
+	if err != nil {
+		r.logger.Error("Failed to get parent spec", zap.Error(err), zap.String("id", spec.ID), zap.String("parent_id", *spec.ParentID))
+		return domain.PresentationSpec{}, err
+	}
+
+	spec.Spec, spec.SheetOptions = mergeSpec(parent, spec.Spec, spec.SheetOptions, spec.RemovedKeys)
 	return spec, nil
 }
 
+// getParent returns the effective spec the spec inherits from, or nil when it has its own copy
+func (r *PgPresentationSpecRepository) getParent(ctx context.Context, id string) (*domain.PresentationSpec, error) {
+	var parentID *string
+	if err := r.conn.QueryRow(ctx, getParentIdQuery, id).Scan(&parentID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get parent id", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+	if parentID == nil {
+		return nil, nil
+	}
+
+	parent, err := r.GetById(ctx, *parentID)
+	if err != nil {
+		r.logger.Error("Failed to get parent spec", zap.Error(err), zap.String("id", id), zap.String("parent_id", *parentID))
+		return nil, err
+	}
+	return &parent, nil
+}
+
+const (
+	defaultListPageSize = 50
+	maxListPageSize     = 200
+)
+
+// List returns a page of specs, most recently updated first, with the count per service of every spec that matches
+func (r *PgPresentationSpecRepository) List(ctx context.Context, params ports.PresentationSpecListParams) (ports.PresentationSpecList, error) {
+	defer r.logger.Sync()
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = defaultListPageSize
+	}
+	if params.PageSize > maxListPageSize {
+		params.PageSize = maxListPageSize
+	}
+
+	filters := []any{params.UserCompany, params.Service, params.DataSource, params.Search, params.IsDefault, params.UpdatedAfter, params.UpdatedBefore}
+
+	rows, err := r.conn.Query(ctx, listQuery, append(filters, params.PageSize, (params.Page-1)*params.PageSize)...)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.Error(err), zap.Any("params", params))
+		return ports.PresentationSpecList{}, err
+	}
+	defer rows.Close()
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.PresentationSpecSummary])
+	if err != nil {
+		r.logger.Error("Got error when collecting rows", zap.Error(err), zap.Any("params", params))
+		return ports.PresentationSpecList{}, err
+	}
+
+	countRows, err := r.conn.Query(ctx, countByServiceQuery, filters...)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.Error(err), zap.Any("params", params))
+		return ports.PresentationSpecList{}, err
+	}
+	defer countRows.Close()
+
+	list := ports.PresentationSpecList{Items: items, Page: params.Page, PageSize: params.PageSize, CountByService: map[string]int{}}
+	for countRows.Next() {
+		var service string
+		var count int
+		if err := countRows.Scan(&service, &count); err != nil {
+			r.logger.Error("Got error when scanning count", zap.Error(err), zap.Any("params", params))
+			return ports.PresentationSpecList{}, err
+		}
+		list.CountByService[service] = count
+		list.Total += count
+	}
+	if err := countRows.Err(); err != nil {
+		r.logger.Error("Got error when reading counts", zap.Error(err), zap.Any("params", params))
+		return ports.PresentationSpecList{}, err
+	}
+
+	return list, nil
+}
+
 func (r *PgPresentationSpecRepository) GetKeyValue(ctx context.Context, id string, key string) (map[string]any, error) {
 	defer r.logger.Sync()
 
@@ -104,7 +202,7 @@ func (r *PgPresentationSpecRepository) GetKeyValue(ctx context.Context, id strin
 
 
 
-func (r *PgPresentationSpecRepository) Add(ctx context.Context, params ports.PresentationSpecQueryParams, body ports.PresentationSpecAddBody) (domain.PresentationSpec, error) {
+func (r *PgPresentationSpecRepository) Add(ctx context.Context, params ports.PresentationSpecQueryParams, body ports.PresentationSpecAddBody, author string) (domain.PresentationSpec, error) {
 	defer r.logger.Sync()
 
 	if params.UserEmail == "" || params.UserCompany == "" || params.Service == "" || params.DataSource == "" {
@@ -137,13 +235,18 @@ func (r *PgPresentationSpecRepository) Add(ctx context.Context, params ports.Pre
 		}
 	}
 
-	new_id := uuid.New().String()
-	rows, err := r.conn.Query(ctx, addBasicInfoQuery, new_id, params.DataSource, params.UserEmail, params.UserCompany, params.Service)
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
+		r.logger.Error("Failed to start transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+	defer tx.Rollback(ctx) // não faz nada depois do commit
+
+	new_id := uuid.New().String()
+	if _, err := tx.Exec(ctx, addBasicInfoQuery, new_id, params.DataSource, params.UserEmail, params.UserCompany, params.Service); err != nil {
 		r.logger.Error("Got error when inserting basic info", zap.Error(err), zap.Any("params", params))
 		return domain.PresentationSpec{}, err
 	}
-	rows.Close()
 
 	for tab, tabSpec := range presentationSpec {
 		var correspondingOptions domain.PresentationSpecSheetOptions
@@ -154,19 +257,24 @@ func (r *PgPresentationSpecRepository) Add(ctx context.Context, params ports.Pre
 			}
 		}
 
-		rows, err := r.conn.Query(ctx, addOptionsQuery, new_id, correspondingOptions.Key, correspondingOptions.ActiveColumns, correspondingOptions.Position, correspondingOptions.ShouldExplode)
-		if err != nil {
+		if _, err := tx.Exec(ctx, addOptionsQuery, new_id, correspondingOptions.Key, correspondingOptions.ActiveColumns, correspondingOptions.Position, correspondingOptions.ShouldExplode, correspondingOptions.ColumnOptions, correspondingOptions.HeaderColor, correspondingOptions.FreezeHeader, correspondingOptions.AutoFilter); err != nil {
 			r.logger.Error("Got error when inserting options", zap.Error(err), zap.Any("params", params))
 			return domain.PresentationSpec{}, err
 		}
-		rows.Close()
 
-		rows, err = r.conn.Query(ctx, addSpecQuery, new_id, correspondingOptions.Key, tabSpec)
-		if err != nil {
+		if _, err := tx.Exec(ctx, addSpecQuery, new_id, correspondingOptions.Key, tabSpec); err != nil {
 			r.logger.Error("Got error when inserting specs", zap.Error(err), zap.Any("params", params))
 			return domain.PresentationSpec{}, err
 		}
-		rows.Close()
+	}
+
+	if err := r.saveVersion(ctx, tx, new_id, author); err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
 	}
 
 	result, _ := r.GetById(ctx, new_id)
@@ -174,7 +282,71 @@ func (r *PgPresentationSpecRepository) Add(ctx context.Context, params ports.Pre
 	return result, nil
 }
 
-func (r *PgPresentationSpecRepository) Patch(ctx context.Context, id string, body ports.PresentationSpecAddBody) (domain.PresentationSpec, error) {
+// Inherit creates a spec for the user that inherits everything from parentId and stores only its own changes
+func (r *PgPresentationSpecRepository) Inherit(ctx context.Context, params ports.PresentationSpecQueryParams, parentId string, author string) (domain.PresentationSpec, error) {
+	defer r.logger.Sync()
+
+	if params.UserEmail == "" || params.UserCompany == "" || params.Service == "" || params.DataSource == "" || parentId == "" {
+		return domain.PresentationSpec{}, ports.NewInvalidQueryParamsError()
+	}
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to start transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+	defer tx.Rollback(ctx) // não faz nada depois do commit
+
+	new_id := uuid.New().String()
+	if _, err := tx.Exec(ctx, addInheritedBasicInfoQuery, new_id, params.DataSource, params.UserEmail, params.UserCompany, params.Service, parentId); err != nil {
+		r.logger.Error("Got error when inserting basic info", zap.Error(err), zap.Any("params", params))
+		return domain.PresentationSpec{}, err
+	}
+
+	if err := r.saveVersion(ctx, tx, new_id, author); err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+
+	return r.GetById(ctx, new_id)
+}
+
+// Import adds the spec for the user, or handles the spec the user already has as onConflict says.
+// merge keeps the existing keys that are not in the body and renumbers the sheet positions
+func (r *PgPresentationSpecRepository) Import(ctx context.Context, params ports.PresentationSpecQueryParams, body ports.PresentationSpecAddBody, onConflict string, author string) (domain.PresentationSpec, error) {
+	defer r.logger.Sync()
+
+	if params.UserEmail == "" || params.UserCompany == "" || params.Service == "" || params.DataSource == "" {
+		return domain.PresentationSpec{}, ports.NewInvalidQueryParamsError()
+	}
+
+	existing, err := r.Get(ctx, params)
+	var notFoundErr repositories.PresentationSpecNotFoundError
+	if errors.As(err, &notFoundErr) || (err == nil && existing.IsDefault) {
+		return r.Add(ctx, params, body, author)
+	}
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	switch onConflict {
+	case ports.ImportConflictReplace:
+		return r.Patch(ctx, existing.ID, body, author)
+	case ports.ImportConflictMerge:
+		spec, sheetOptions := mergeSpec(existing, body.PresentationSpec, body.SpecOptions, nil)
+		for i := range sheetOptions {
+			sheetOptions[i].Position = i
+		}
+		return r.Patch(ctx, existing.ID, ports.PresentationSpecAddBody{PresentationSpec: spec, SpecOptions: sheetOptions}, author)
+	}
+	return domain.PresentationSpec{}, repositories.NewPresentationSpecConflictError()
+}
+
+func (r *PgPresentationSpecRepository) Patch(ctx context.Context, id string, body ports.PresentationSpecAddBody, author string) (domain.PresentationSpec, error) {
 	defer r.logger.Sync()
 
 	presentationSpec := body.PresentationSpec
@@ -207,23 +379,24 @@ func (r *PgPresentationSpecRepository) Patch(ctx context.Context, id string, bod
 		}
 	}
 
+	parent, err := r.getParent(ctx, id)
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+	overrides, removedKeys := getOverrides(parent, body)
+
 	tx, err := r.conn.Begin(ctx)
 	if err != nil {
 		r.logger.Error("Failed to start transaction", zap.Error(err))
 		return domain.PresentationSpec{}, err
 	}
+	defer tx.Rollback(ctx) // não faz nada depois do commit
 
-	defer func() {
-		if err != nil {
-			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
-				r.logger.Error("Failed to roll back transaction", zap.Error(rollbackErr))
-			}
-		} else {
-			if commitErr := tx.Commit(ctx); commitErr != nil {
-				r.logger.Error("Failed to commit transaction", zap.Error(commitErr))
-			}
-		}
-	}()
+	// specs anteriores ao histórico ainda não têm a versão atual salva
+	if _, err := tx.Exec(ctx, addVersionQuery, id, ""); err != nil {
+		r.logger.Error("Got error when saving previous version", zap.Error(err), zap.Any("params", id))
+		return domain.PresentationSpec{}, err
+	}
 
 	if _, err := tx.Exec(ctx, deleteSpecsQuery, id); err != nil {
 		r.logger.Error("Got error when deleting specs", zap.Error(err), zap.Any("params", id))
@@ -240,32 +413,41 @@ func (r *PgPresentationSpecRepository) Patch(ctx context.Context, id string, bod
 		return domain.PresentationSpec{}, err
 	}
 
-	for tab, tabSpec := range presentationSpec {
-		var correspondingOptions domain.PresentationSpecSheetOptions
-		for _, options := range sheetOptions {
-			if tab == options.Key {
-				correspondingOptions = options
-				break
-			}
-		}
+	if _, err := tx.Exec(ctx, patchRemovedKeysQuery, id, removedKeys); err != nil {
+		r.logger.Error("Got error patching removed keys", zap.Error(err), zap.Any("params", id))
+		return domain.PresentationSpec{}, err
+	}
 
-		if _, err := tx.Exec(ctx, addOptionsQuery, id, correspondingOptions.Key, correspondingOptions.ActiveColumns, correspondingOptions.Position, correspondingOptions.ShouldExplode); err != nil {
+	for _, options := range overrides.SpecOptions {
+		if _, err := tx.Exec(ctx, addOptionsQuery, id, options.Key, options.ActiveColumns, options.Position, options.ShouldExplode, options.ColumnOptions, options.HeaderColor, options.FreezeHeader, options.AutoFilter); err != nil {
 			r.logger.Error("Got error when inserting options", zap.Error(err), zap.Any("params", id))
 			return domain.PresentationSpec{}, err
 		}
+	}
 
-		if _, err := tx.Exec(ctx, addSpecQuery, id, correspondingOptions.Key, tabSpec); err != nil {
+	for key, keySpec := range overrides.PresentationSpec {
+		if _, err := tx.Exec(ctx, addSpecQuery, id, key, keySpec); err != nil {
 			r.logger.Error("Got error when inserting specs", zap.Error(err), zap.Any("params", id))
 			return domain.PresentationSpec{}, err
 		}
 	}
 
+	if _, err := tx.Exec(ctx, addVersionQuery, id, author); err != nil {
+		r.logger.Error("Got error when saving version", zap.Error(err), zap.Any("params", id))
+		return domain.PresentationSpec{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+
 	patchedSpec, _ := r.GetById(ctx, id)
 
 	return patchedSpec, nil
 }
 
-func (r *PgPresentationSpecRepository) PatchSource(ctx context.Context, id string, body ports.PresentationSpecPatchSource) (domain.PresentationSpec, error) {
+func (r *PgPresentationSpecRepository) PatchSource(ctx context.Context, id string, body ports.PresentationSpecPatchSource, author string) (domain.PresentationSpec, error) {
 	defer r.logger.Sync()
 
 	if id == "" {
@@ -280,6 +462,26 @@ func (r *PgPresentationSpecRepository) PatchSource(ctx context.Context, id strin
 		"contacts": body.ContactsSource,
 	}
 
+	parent, err := r.getParent(ctx, id)
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+	if parent != nil {
+		return r.patchInheritedSource(ctx, id, keys, author)
+	}
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		r.logger.Error("Failed to start transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+	defer tx.Rollback(ctx) // não faz nada depois do commit
+
+	// specs anteriores ao histórico ainda não têm a versão atual salva
+	if err := r.saveVersion(ctx, tx, id, ""); err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
 	for key, sourceID := range keys {
 		if sourceID == nil || sourceID == "" {
 			continue
@@ -294,54 +496,23 @@ func (r *PgPresentationSpecRepository) PatchSource(ctx context.Context, id strin
 			continue
 		}
 	
-		if key == "contacts" {
-			flatSlice, ok := existingValue["$flat"].([]any)
-			if !ok {
-				flatSlice = []any{}
-			}
-	
-			for i, item := range flatSlice {
-				itemMap, ok := item.(map[string]any)
-				if !ok {
-					continue
-				}
-	
-				forMap, ok := itemMap["$for"].(map[string]any)
-				if !ok {
-					continue
-				}
-	
-				entityMap, ok := forMap["$format"].(map[string]any)["entity"].(map[string]any)
-				if !ok {
-					continue
-				}
-	
-				entityMap["SOURCE_ID"] = map[string]any{
-					"$literal": sourceID,
-				}
-				flatSlice[i] = itemMap
-			}
-	
-			existingValue["$flat"] = flatSlice
-		} else {
-			entityMap, ok := existingValue["entity"].(map[string]any)
-			if !ok {
-				entityMap = make(map[string]any)
-				existingValue["entity"] = entityMap
-			}
-	
-			entityMap["SOURCE_ID"] = map[string]any{
-				"$literal": sourceID,
-			}
-		}
-	
-		_, err = r.conn.Query(ctx, patchKeyValueQuery, existingValue, key, id)
-		if err != nil {
+		setSourceID(key, existingValue, sourceID)
+
+		if _, err := tx.Exec(ctx, patchKeyValueQuery, existingValue, key, id); err != nil {
 			r.logger.Error("Failed to update key value", zap.String("key", key), zap.Error(err))
 			return domain.PresentationSpec{}, err
 		}
 	}
 
+	if err := r.bumpVersion(ctx, tx, id, author); err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+
 	updatedSpec, err := r.GetById(ctx, id)
 	if err != nil {
 		r.logger.Error("Failed to retrieve updated spec", zap.Error(err))
@@ -352,35 +523,229 @@ func (r *PgPresentationSpecRepository) PatchSource(ctx context.Context, id strin
 }
 
 
-func (r *PgPresentationSpecRepository) PatchKey(ctx context.Context, id string, key string, body ports.PresentationSpecPatchKey) (domain.PresentationSpec, error) {
+// patchInheritedSource sets the sources in the effective spec, keys inherited from the parent become overrides
+func (r *PgPresentationSpecRepository) patchInheritedSource(ctx context.Context, id string, keys map[string]any, author string) (domain.PresentationSpec, error) {
+	current, err := r.GetById(ctx, id)
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	for key, sourceID := range keys {
+		value, exists := current.Spec[key]
+		if sourceID == nil || sourceID == "" || !exists {
+			continue
+		}
+		setSourceID(key, value, sourceID)
+	}
+
+	return r.Patch(ctx, id, ports.PresentationSpecAddBody{PresentationSpec: current.Spec, SpecOptions: current.SheetOptions}, author)
+}
+
+// setSourceID sets the SOURCE_ID of the entities the key creates in the crm, contacts sets it for every contact
+func setSourceID(key string, value map[string]any, sourceID any) {
+	if key == "contacts" {
+		flatSlice, ok := value["$flat"].([]any)
+		if !ok {
+			flatSlice = []any{}
+		}
+
+		for i, item := range flatSlice {
+			itemMap, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			forMap, ok := itemMap["$for"].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			entityMap, ok := forMap["$format"].(map[string]any)["entity"].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			entityMap["SOURCE_ID"] = map[string]any{
+				"$literal": sourceID,
+			}
+			flatSlice[i] = itemMap
+		}
+
+		value["$flat"] = flatSlice
+	} else {
+		entityMap, ok := value["entity"].(map[string]any)
+		if !ok {
+			entityMap = make(map[string]any)
+			value["entity"] = entityMap
+		}
+
+		entityMap["SOURCE_ID"] = map[string]any{
+			"$literal": sourceID,
+		}
+	}
+}
+
+func (r *PgPresentationSpecRepository) PatchKey(ctx context.Context, id string, key string, body ports.PresentationSpecPatchKey, author string) (domain.PresentationSpec, error) {
 	defer r.logger.Sync()
 
 	if id == "" || key == "" {
 		return domain.PresentationSpec{}, ports.NewInvalidQueryParamsError()
 	}
 
+	parent, err := r.getParent(ctx, id)
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+	if parent != nil {
+		return r.patchInheritedKey(ctx, id, key, body, author)
+	}
+
 	spec := body.PresentationSpec
 	options := body.SpecOptions
 
-	rows, err := r.conn.Query(ctx, patchKeyOptions, options.Key, options.ActiveColumns, options.Position, options.ShouldExplode, id, key)
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
+		r.logger.Error("Failed to start transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
+	defer tx.Rollback(ctx) // não faz nada depois do commit
+
+	// specs anteriores ao histórico ainda não têm a versão atual salva
+	if err := r.saveVersion(ctx, tx, id, ""); err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	if _, err := tx.Exec(ctx, patchKeyOptions, options.Key, options.ActiveColumns, options.Position, options.ShouldExplode, options.ColumnOptions, options.HeaderColor, options.FreezeHeader, options.AutoFilter, id, key); err != nil {
 		r.logger.Error("Got error when updating sheet options", zap.Error(err), zap.Any("params", id))
 		return domain.PresentationSpec{}, err
 	}
-	rows.Close()
 
-	rows, err = r.conn.Query(ctx, patchKeySpec, spec, options.Key, id, key)
-	if err != nil {
+	if _, err := tx.Exec(ctx, patchKeySpec, spec, options.Key, id, key); err != nil {
 		r.logger.Error("Got error when updating key spec", zap.Error(err), zap.Any("params", id))
 		return domain.PresentationSpec{}, err
 	}
-	rows.Close()
+
+	if err := r.bumpVersion(ctx, tx, id, author); err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit transaction", zap.Error(err))
+		return domain.PresentationSpec{}, err
+	}
 
 	updatedSpec, _ := r.GetById(ctx, id)
 
 	return updatedSpec, nil
 }
 
+// patchInheritedKey replaces the key in the effective spec, an unknown key changes nothing as in PatchKey
+func (r *PgPresentationSpecRepository) patchInheritedKey(ctx context.Context, id string, key string, body ports.PresentationSpecPatchKey, author string) (domain.PresentationSpec, error) {
+	current, err := r.GetById(ctx, id)
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+	if _, exists := current.Spec[key]; !exists {
+		return current, nil
+	}
+
+	delete(current.Spec, key)
+	current.Spec[body.SpecOptions.Key] = body.PresentationSpec
+	for i, options := range current.SheetOptions {
+		if options.Key == key {
+			current.SheetOptions[i] = domain.PresentationSpecSheetOptions(body.SpecOptions)
+		}
+	}
+
+	return r.Patch(ctx, id, ports.PresentationSpecAddBody{PresentationSpec: current.Spec, SpecOptions: current.SheetOptions}, author)
+}
+
+// GetVersions lists the saved versions of a spec, newest first, without their specs
+func (r *PgPresentationSpecRepository) GetVersions(ctx context.Context, id string) ([]domain.PresentationSpecVersion, error) {
+	defer r.logger.Sync()
+
+	if id == "" {
+		return nil, ports.NewInvalidQueryParamsError()
+	}
+
+	rows, err := r.conn.Query(ctx, getVersionsQuery, id)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.Error(err), zap.Any("params", id))
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.PresentationSpecVersion])
+	if err != nil {
+		r.logger.Error("Got error when collecting rows", zap.Error(err), zap.Any("params", id))
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetVersion returns a saved version, versions of inherited specs are resolved against the current parent
+func (r *PgPresentationSpecRepository) GetVersion(ctx context.Context, id string, version int) (domain.PresentationSpecVersion, error) {
+	defer r.logger.Sync()
+
+	if id == "" {
+		return domain.PresentationSpecVersion{}, ports.NewInvalidQueryParamsError()
+	}
+
+	rows, err := r.conn.Query(ctx, getVersionQuery, id, version)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.Error(err), zap.Any("params", id))
+		return domain.PresentationSpecVersion{}, err
+	}
+	defer rows.Close()
+
+	specVersion, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[domain.PresentationSpecVersion])
+	if err != nil {
+		r.logger.Error("Got error when collecting one row", zap.Error(err), zap.Any("params", id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.PresentationSpecVersion{}, repositories.NewPresentationSpecVersionNotFoundError()
+		}
+		return domain.PresentationSpecVersion{}, err
+	}
+
+	parent, err := r.getParent(ctx, id)
+	if err != nil {
+		return domain.PresentationSpecVersion{}, err
+	}
+	if parent != nil {
+		specVersion.Spec, specVersion.SheetOptions = mergeSpec(*parent, specVersion.Spec, specVersion.SheetOptions, specVersion.RemovedKeys)
+	}
+	return specVersion, nil
+}
+
+// Rollback patches the spec with the content of a saved version, which is saved again as a new version
+func (r *PgPresentationSpecRepository) Rollback(ctx context.Context, id string, version int, author string) (domain.PresentationSpec, error) {
+	specVersion, err := r.GetVersion(ctx, id, version)
+	if err != nil {
+		return domain.PresentationSpec{}, err
+	}
+
+	return r.Patch(ctx, id, ports.PresentationSpecAddBody{PresentationSpec: specVersion.Spec, SpecOptions: specVersion.SheetOptions}, author)
+}
+
+// saveVersion saves the current content of the spec as its version, inside the transaction of the change
+func (r *PgPresentationSpecRepository) saveVersion(ctx context.Context, tx pgx.Tx, id string, author string) error {
+	if _, err := tx.Exec(ctx, addVersionQuery, id, author); err != nil {
+		r.logger.Error("Got error when saving version", zap.Error(err), zap.Any("params", id))
+		return err
+	}
+	return nil
+}
+
+// bumpVersion increments the version after a change and saves it
+func (r *PgPresentationSpecRepository) bumpVersion(ctx context.Context, tx pgx.Tx, id string, author string) error {
+	if _, err := tx.Exec(ctx, patchBasicInfo, id); err != nil {
+		r.logger.Error("Got error patching basic_info", zap.Error(err), zap.Any("params", id))
+		return err
+	}
+	return r.saveVersion(ctx, tx, id, author)
+}
+
+// Delete refuses to delete a spec other specs inherit from, they would lose the keys they don't override
 func (r *PgPresentationSpecRepository) Delete(ctx context.Context, id string) error {
 	defer r.logger.Sync()
 
@@ -388,12 +753,19 @@ func (r *PgPresentationSpecRepository) Delete(ctx context.Context, id string) er
 		return ports.NewInvalidQueryParamsError()
 	}
 
-	rows, err := r.conn.Query(ctx, deleteQuery, id)
-	if err != nil {
+	var hasChildren bool
+	if err := r.conn.QueryRow(ctx, hasChildrenQuery, id).Scan(&hasChildren); err != nil {
+		r.logger.Error("Got error when checking children", zap.Error(err), zap.Any("params", id))
+		return err
+	}
+	if hasChildren {
+		return repositories.NewPresentationSpecHasChildrenError()
+	}
+
+	if _, err := r.conn.Exec(ctx, deleteQuery, id); err != nil {
 		r.logger.Error("Got error when deleting basic info", zap.Error(err), zap.Any("params", id))
 		return err
 	}
-	rows.Close()
 
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"export-service/internal/core/domain"
 	"export-service/internal/core/ports"
+	"export-service/internal/repositories"
 	"export-service/internal/repositories/presentation_spec_repo"
 	"log"
 	"testing"
@@ -194,7 +195,7 @@ func TestAdd(t *testing.T) {
 	// Begin testing
 	t.Run("Should add user's custom spec", func(t *testing.T) {
 
-		result, err := repo.Add(ctx, ports.PresentationSpecQueryParams{UserEmail: "francisco.becheli@driva.com.br", UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec})
+		result, err := repo.Add(ctx, ports.PresentationSpecQueryParams{UserEmail: "francisco.becheli@driva.com.br", UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec}, "francisco.becheli@driva.com.br")
 
 		require.NoError(t, err)
 		require.Equal(t, result.SheetOptions, sheetOptions)
@@ -207,7 +208,7 @@ func TestAdd(t *testing.T) {
 
 	t.Run("Should return error if invalid params", func(t *testing.T) {
 
-		_, err := repo.Add(ctx, ports.PresentationSpecQueryParams{UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec})
+		_, err := repo.Add(ctx, ports.PresentationSpecQueryParams{UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec}, "francisco.becheli@driva.com.br")
 
 		var invalidErr ports.InvalidQueryParamsError
 		require.Error(t, err)
@@ -277,11 +278,26 @@ func TestDelete(t *testing.T) {
 		"Telefones": {"CNPJ": "cnpj", "Razão Social": "razao_social", "Telefone Completo": "1111-1111"},
 	}
 
-	result, _ := repo.Add(ctx, ports.PresentationSpecQueryParams{UserEmail: "francisco.becheli@driva.com.br", UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec})
+	result, _ := repo.Add(ctx, ports.PresentationSpecQueryParams{UserEmail: "francisco.becheli@driva.com.br", UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec}, "francisco.becheli@driva.com.br")
 
 	specId := result.ID
 
 	// Begin testing
+	t.Run("Should refuse to delete a spec another spec inherits from", func(t *testing.T) {
+		child, err := repo.Inherit(ctx, ports.PresentationSpecQueryParams{UserEmail: "fulano@driva.com.br", UserCompany: "Driva", Service: "enrichment", DataSource: "empresas"}, specId, "fulano@driva.com.br")
+		require.NoError(t, err)
+
+		err = repo.Delete(ctx, specId)
+		var hasChildren repositories.PresentationSpecHasChildrenError
+		require.ErrorAs(t, err, &hasChildren)
+
+		resolved, err := repo.GetById(ctx, child.ID)
+		require.NoError(t, err)
+		require.Equal(t, spec, resolved.Spec)
+
+		require.NoError(t, repo.Delete(ctx, child.ID))
+	})
+
 	t.Run("Should delete user's custom spec", func(t *testing.T) {
 
 		err := repo.Delete(ctx, specId)
@@ -289,3 +305,357 @@ func TestDelete(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Setup postgres container
+
+	postgresContainer, err := postgres.Run(ctx,
+		"docker.io/postgres:15-alpine",
+		postgres.WithDatabase("exports"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		postgres.WithInitScripts("seed.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		log.Fatalf("failed to start container: %s", err)
+	}
+
+	// Clean up the container
+	defer func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			log.Fatalf("failed to terminate container: %s", err)
+		}
+	}()
+	url, _ := postgresContainer.ConnectionString(ctx)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		log.Fatalf("Unable to parse connection string: %v", err)
+	}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		log.Fatalf("Unable to create connection pool: %v", err)
+	}
+	defer conn.Close()
+	logger, _ := zap.NewProduction()
+	repo := presentation_spec_repo.NewPgPresentationSpecRepository(conn, logger)
+
+	specId := "123e4567-e89b-12d3-a456-426655440000"
+	sheetOptions := []domain.PresentationSpecSheetOptions{
+		{
+			Key:           "RFB",
+			ActiveColumns: []string{"CNPJ", "Razão Social"},
+			Position:      0,
+			ShouldExplode: false,
+		},
+	}
+	spec := domain.PresentationSpecSpec{
+		"RFB": {"CNPJ": "cnpj", "Razão Social": "razao_social"},
+	}
+
+	// Begin testing
+	t.Run("Should save the previous and the new version on patch", func(t *testing.T) {
+		result, err := repo.Patch(ctx, specId, ports.PresentationSpecAddBody{SpecOptions: sheetOptions, PresentationSpec: spec}, "victor@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, 3, result.Version)
+		require.Equal(t, spec, result.Spec)
+
+		versions, err := repo.GetVersions(ctx, specId)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		require.Equal(t, 3, versions[0].Version)
+		require.Equal(t, "victor@driva.com.br", versions[0].Author)
+		require.Equal(t, 2, versions[1].Version)
+		require.Empty(t, versions[1].Author)
+	})
+
+	t.Run("Should rollback to a previous version as a new version", func(t *testing.T) {
+		result, err := repo.Rollback(ctx, specId, 2, "victor@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, 4, result.Version)
+		require.Equal(t, domain.PresentationSpecSpec{"RFB": {"CNPJ": "cnpj"}}, result.Spec)
+
+		version, err := repo.GetVersion(ctx, specId, 4)
+		require.NoError(t, err)
+		require.Equal(t, result.Spec, version.Spec)
+	})
+
+	t.Run("Should return error for unknown version", func(t *testing.T) {
+		_, err := repo.Rollback(ctx, specId, 99, "victor@driva.com.br")
+
+		var notFoundErr repositories.PresentationSpecVersionNotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+}
+
+func TestInheritance(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Setup postgres container
+
+	postgresContainer, err := postgres.Run(ctx,
+		"docker.io/postgres:15-alpine",
+		postgres.WithDatabase("exports"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		postgres.WithInitScripts("seed.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		log.Fatalf("failed to start container: %s", err)
+	}
+
+	// Clean up the container
+	defer func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			log.Fatalf("failed to terminate container: %s", err)
+		}
+	}()
+	url, _ := postgresContainer.ConnectionString(ctx)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		log.Fatalf("Unable to parse connection string: %v", err)
+	}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		log.Fatalf("Unable to create connection pool: %v", err)
+	}
+	defer conn.Close()
+	logger, _ := zap.NewProduction()
+	repo := presentation_spec_repo.NewPgPresentationSpecRepository(conn, logger)
+
+	defaultId := "123e4567-e89b-12d3-a456-426655440001"
+	params := ports.PresentationSpecQueryParams{
+		UserEmail:   "ana@driva.com.br",
+		UserCompany: "Driva",
+		Service:     "enrichment_test",
+		DataSource:  "empresas",
+	}
+
+	var childId string
+
+	// Begin testing
+	t.Run("Should inherit the default spec", func(t *testing.T) {
+		result, err := repo.Inherit(ctx, params, defaultId, "ana@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, defaultId, *result.ParentID)
+		require.Equal(t, domain.PresentationSpecSpec{"RFB": {"CNPJ": "cnpj", "Nome": "razao_social"}}, result.Spec)
+		childId = result.ID
+
+		userSpec, err := repo.Get(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, childId, userSpec.ID)
+	})
+
+	t.Run("Should keep the overrides of the child", func(t *testing.T) {
+		spec := domain.PresentationSpecSpec{
+			"RFB":    {"CNPJ": "cnpj", "Nome": "razao_social"},
+			"Emails": {"Email": "email"},
+		}
+		sheetOptions := []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ", "Nome"}, Position: 0},
+			{Key: "Emails", ActiveColumns: []string{"Email"}, Position: 1},
+		}
+
+		result, err := repo.Patch(ctx, childId, ports.PresentationSpecAddBody{PresentationSpec: spec, SpecOptions: sheetOptions}, "ana@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, spec, result.Spec)
+		require.Equal(t, sheetOptions, result.SheetOptions)
+	})
+
+	t.Run("Should receive later changes to the default", func(t *testing.T) {
+		_, err := repo.Patch(ctx, defaultId, ports.PresentationSpecAddBody{
+			PresentationSpec: domain.PresentationSpecSpec{"RFB": {"CNPJ": "cnpj", "Nome": "razao_social", "Site": "site"}},
+			SpecOptions:      []domain.PresentationSpecSheetOptions{{Key: "RFB", ActiveColumns: []string{"CNPJ", "Nome", "Site"}, Position: 0}},
+		}, "victor@driva.com.br")
+		require.NoError(t, err)
+
+		result, err := repo.GetById(ctx, childId)
+		require.NoError(t, err)
+		require.Equal(t, domain.PresentationSpecSpec{
+			"RFB":    {"CNPJ": "cnpj", "Nome": "razao_social", "Site": "site"},
+			"Emails": {"Email": "email"},
+		}, result.Spec)
+		require.Equal(t, []string{"CNPJ", "Nome", "Site"}, result.SheetOptions[0].ActiveColumns)
+	})
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Setup postgres container
+
+	postgresContainer, err := postgres.Run(ctx,
+		"docker.io/postgres:15-alpine",
+		postgres.WithDatabase("exports"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		postgres.WithInitScripts("seed.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		log.Fatalf("failed to start container: %s", err)
+	}
+
+	// Clean up the container
+	defer func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			log.Fatalf("failed to terminate container: %s", err)
+		}
+	}()
+	url, _ := postgresContainer.ConnectionString(ctx)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		log.Fatalf("Unable to parse connection string: %v", err)
+	}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		log.Fatalf("Unable to create connection pool: %v", err)
+	}
+	defer conn.Close()
+	logger, _ := zap.NewProduction()
+	repo := presentation_spec_repo.NewPgPresentationSpecRepository(conn, logger)
+
+	params := ports.PresentationSpecQueryParams{
+		UserEmail:   "victor@driva.com.br",
+		UserCompany: "Driva",
+		Service:     "enrichment_test",
+		DataSource:  "empresas",
+	}
+	document := ports.PresentationSpecAddBody{
+		PresentationSpec: domain.PresentationSpecSpec{"Emails": {"Email": "email"}},
+		SpecOptions:      []domain.PresentationSpecSheetOptions{{Key: "Emails", ActiveColumns: []string{"Email"}, Position: 0}},
+	}
+
+	// Begin testing
+	t.Run("Should fail when the user already has a spec", func(t *testing.T) {
+		_, err := repo.Import(ctx, params, document, ports.ImportConflictFail, "victor@driva.com.br")
+
+		var conflictErr repositories.PresentationSpecConflictError
+		require.ErrorAs(t, err, &conflictErr)
+	})
+
+	t.Run("Should merge with the existing spec", func(t *testing.T) {
+		result, err := repo.Import(ctx, params, document, ports.ImportConflictMerge, "victor@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, "123e4567-e89b-12d3-a456-426655440000", result.ID)
+		require.Equal(t, domain.PresentationSpecSpec{
+			"RFB":    {"CNPJ": "cnpj"},
+			"Emails": {"Email": "email"},
+		}, result.Spec)
+		require.Len(t, result.SheetOptions, 2)
+	})
+
+	t.Run("Should replace the existing spec", func(t *testing.T) {
+		result, err := repo.Import(ctx, params, document, ports.ImportConflictReplace, "victor@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, "123e4567-e89b-12d3-a456-426655440000", result.ID)
+		require.Equal(t, document.PresentationSpec, result.Spec)
+	})
+
+	t.Run("Should add the spec for a new user", func(t *testing.T) {
+		newParams := params
+		newParams.UserEmail = "ana@driva.com.br"
+
+		result, err := repo.Import(ctx, newParams, document, ports.ImportConflictFail, "victor@driva.com.br")
+		require.NoError(t, err)
+		require.Equal(t, "ana@driva.com.br", result.UserEmail)
+		require.Equal(t, document.PresentationSpec, result.Spec)
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Setup postgres container
+
+	postgresContainer, err := postgres.Run(ctx,
+		"docker.io/postgres:15-alpine",
+		postgres.WithDatabase("exports"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		postgres.WithInitScripts("seed.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		log.Fatalf("failed to start container: %s", err)
+	}
+
+	// Clean up the container
+	defer func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			log.Fatalf("failed to terminate container: %s", err)
+		}
+	}()
+	url, _ := postgresContainer.ConnectionString(ctx)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		log.Fatalf("Unable to parse connection string: %v", err)
+	}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		log.Fatalf("Unable to create connection pool: %v", err)
+	}
+	defer conn.Close()
+	logger, _ := zap.NewProduction()
+	repo := presentation_spec_repo.NewPgPresentationSpecRepository(conn, logger)
+
+	// Begin testing
+	t.Run("Should list every spec with the count per service", func(t *testing.T) {
+		result, err := repo.List(ctx, ports.PresentationSpecListParams{})
+		require.NoError(t, err)
+		require.Len(t, result.Items, 2)
+		require.Equal(t, 2, result.Total)
+		require.Equal(t, map[string]int{"enrichment_test": 2}, result.CountByService)
+		require.Equal(t, 1, result.Page)
+	})
+
+	t.Run("Should filter by is_default and search", func(t *testing.T) {
+		isDefault := false
+		result, err := repo.List(ctx, ports.PresentationSpecListParams{IsDefault: &isDefault, Search: "victor"})
+		require.NoError(t, err)
+		require.Len(t, result.Items, 1)
+		require.Equal(t, "123e4567-e89b-12d3-a456-426655440000", result.Items[0].ID)
+	})
+
+	t.Run("Should filter by updated date", func(t *testing.T) {
+		updatedAfter := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		result, err := repo.List(ctx, ports.PresentationSpecListParams{UpdatedAfter: &updatedAfter})
+		require.NoError(t, err)
+		require.Empty(t, result.Items)
+		require.Equal(t, 0, result.Total)
+	})
+
+	t.Run("Should paginate", func(t *testing.T) {
+		result, err := repo.List(ctx, ports.PresentationSpecListParams{Page: 2, PageSize: 1})
+		require.NoError(t, err)
+		require.Len(t, result.Items, 1)
+		require.Equal(t, 2, result.Total)
+	})
+}
@@ -6,7 +6,7 @@ const getQuery = `
 		group by presentation_spec_id
 	),
 	options as (
-		select presentation_spec_id as id, jsonb_agg(jsonb_build_object('key', key,'active_columns', active_columns , 'position', position, 'should_explode', should_explode) order by position) as sheet_options from presentation_spec.sheet_options
+		select presentation_spec_id as id, jsonb_agg(jsonb_build_object('key', key,'active_columns', active_columns , 'position', position, 'should_explode', should_explode, 'column_options', column_options, 'header_color', header_color, 'freeze_header', freeze_header, 'auto_filter', auto_filter) order by position) as sheet_options from presentation_spec.sheet_options
 		group by presentation_spec_id
 	),
 	basic_info_with_default as (
@@ -15,7 +15,9 @@ const getQuery = `
 		order by is_default -- first not default
 		limit 1
 	)
-	SELECT * FROM basic_info_with_default inner join spec using (id) inner join options using (id)
+	-- specs que herdam de um default podem não ter nenhuma chave própria
+	SELECT basic_info_with_default.*, coalesce(spec.spec, '{}') as spec, coalesce(options.sheet_options, '[]') as sheet_options
+	FROM basic_info_with_default left join spec using (id) left join options using (id)
 	`
 
 const getByIdQuery = `
@@ -24,7 +26,7 @@ with spec as (
 	group by presentation_spec_id
 ),
 options as (
-	select presentation_spec_id as id, jsonb_agg(jsonb_build_object('key', key,'active_columns', active_columns , 'position', position, 'should_explode', should_explode) order by position) as sheet_options from presentation_spec.sheet_options
+	select presentation_spec_id as id, jsonb_agg(jsonb_build_object('key', key,'active_columns', active_columns , 'position', position, 'should_explode', should_explode, 'column_options', column_options, 'header_color', header_color, 'freeze_header', freeze_header, 'auto_filter', auto_filter) order by position) as sheet_options from presentation_spec.sheet_options
 	group by presentation_spec_id
 ),
 basic_info_with_default as (
@@ -33,21 +35,59 @@ basic_info_with_default as (
 	order by is_default -- first not default
 	limit 1
 )
-SELECT * FROM basic_info_with_default inner join spec using (id) inner join options using (id)
+SELECT basic_info_with_default.*, coalesce(spec.spec, '{}') as spec, coalesce(options.sheet_options, '[]') as sheet_options
+FROM basic_info_with_default left join spec using (id) left join options using (id)
+`
+
+const listFilters = `
+	($1 = '' OR user_company = $1) AND ($2 = '' OR service = $2) AND ($3 = '' OR base = $3)
+	AND ($4 = '' OR user_email ilike '%' || $4 || '%' OR user_company ilike '%' || $4 || '%')
+	AND ($5::bool is null OR is_default = $5)
+	AND ($6::timestamptz is null OR updated_at >= $6) AND ($7::timestamptz is null OR updated_at < $7)
+`
+
+const listQuery = `
+	select id, version, base, user_email, user_company, service, coalesce(is_default, false) as is_default, parent_id, created_at, updated_at
+	from presentation_spec.basic_info
+	where ` + listFilters + `
+	order by updated_at desc, id
+	limit $8 offset $9;
+`
+
+const countByServiceQuery = `
+	select service, count(*) from presentation_spec.basic_info
+	where ` + listFilters + `
+	group by service;
 `
 
 const addBasicInfoQuery = `
 	insert into presentation_spec.basic_info (id, base, user_email, user_company, service, is_default) values ($1, $2, $3, $4, $5, false);
 `
 
+const addInheritedBasicInfoQuery = `
+	insert into presentation_spec.basic_info (id, base, user_email, user_company, service, is_default, parent_id) values ($1, $2, $3, $4, $5, false, $6);
+`
+
+const getParentIdQuery = `
+	select parent_id from presentation_spec.basic_info where id = $1;
+`
+
+const patchRemovedKeysQuery = `
+	update presentation_spec.basic_info set removed_keys = $2 where id = $1;
+`
+
 const addOptionsQuery = `
-	insert into presentation_spec.sheet_options (presentation_spec_id, key, active_columns, position, should_explode) values ($1, $2, $3, $4, $5);
+	insert into presentation_spec.sheet_options (presentation_spec_id, key, active_columns, position, should_explode, column_options, header_color, freeze_header, auto_filter) values ($1, $2, $3, $4, $5, $6, $7, $8, $9);
 `
 
 const addSpecQuery = `
 	insert into presentation_spec.specs (presentation_spec_id, key, value) values ($1, $2, $3);
 `
 
+const hasChildrenQuery = `
+	select exists (select 1 from presentation_spec.basic_info where parent_id = $1);
+`
+
 const deleteQuery = `
 	delete from presentation_spec.basic_info where id = $1;
 `
@@ -57,11 +97,34 @@ const deleteSpecsQuery = `delete from presentation_spec.specs where presentation
 const deleteSheetOptionsQuery = `delete from presentation_spec.sheet_options where presentation_spec_id = $1`
 
 const patchBasicInfo = `
-	update presentation_spec.basic_info set updated_at = now() where id = $1;
+	update presentation_spec.basic_info set updated_at = now(), version = version + 1 where id = $1;
+`
+
+// addVersionQuery snapshots the current spec, ignoring versions already saved
+const addVersionQuery = `
+	insert into presentation_spec.versions (presentation_spec_id, version, spec, sheet_options, removed_keys, author)
+	select id, version,
+		(select coalesce(jsonb_object_agg(key, value), '{}') from presentation_spec.specs where presentation_spec_id = $1),
+		(select coalesce(jsonb_agg(jsonb_build_object('key', key,'active_columns', active_columns , 'position', position, 'should_explode', should_explode, 'column_options', column_options, 'header_color', header_color, 'freeze_header', freeze_header, 'auto_filter', auto_filter) order by position), '[]') from presentation_spec.sheet_options where presentation_spec_id = $1),
+		removed_keys,
+		$2
+	from presentation_spec.basic_info where id = $1
+	on conflict (presentation_spec_id, version) do nothing;
+`
+
+const getVersionsQuery = `
+	select presentation_spec_id, version, null::jsonb as spec, null::jsonb as sheet_options, null::text[] as removed_keys, author, created_at from presentation_spec.versions
+	where presentation_spec_id = $1
+	order by version desc;
+`
+
+const getVersionQuery = `
+	select presentation_spec_id, version, spec, sheet_options, removed_keys, author, created_at from presentation_spec.versions
+	where presentation_spec_id = $1 and version = $2;
 `
 
 const patchKeyOptions = `
-	update presentation_spec.sheet_options set key = $1, active_columns = $2, position = $3, should_explode = $4 where presentation_spec_id = $5 and key = $6;
+	update presentation_spec.sheet_options set key = $1, active_columns = $2, position = $3, should_explode = $4, column_options = $5, header_color = $6, freeze_header = $7, auto_filter = $8 where presentation_spec_id = $9 and key = $10;
 `
 
 const patchKeyValueQuery = `
@@ -14,6 +14,8 @@ create table presentation_spec.basic_info (
         user_company text not null,
         service text not null,
         is_default bool default false,
+        parent_id UUID references presentation_spec.basic_info (id),
+        removed_keys text[],
         unique (
             user_email,
             user_company,
@@ -25,7 +27,7 @@ create table presentation_spec.basic_info (
 create unique index unique_default_spec_idx on presentation_spec.basic_info (service, base)
 where (is_default);
 
-create table presentation_spec.sheet_options (presentation_spec_id UUID references presentation_spec.basic_info (id) on delete cascade, key text, active_columns text[], position integer, should_explode bool);
+create table presentation_spec.sheet_options (presentation_spec_id UUID references presentation_spec.basic_info (id) on delete cascade, key text, active_columns text[], position integer, should_explode bool, column_options jsonb, header_color text, freeze_header bool default false, auto_filter bool default false);
 
 create table presentation_spec.specs (
     presentation_spec_id UUID references presentation_spec.basic_info (id) on delete cascade,
@@ -33,6 +35,19 @@ create table presentation_spec.specs (
     value JSONB
 );
 
+create table presentation_spec.versions (
+    presentation_spec_id UUID references presentation_spec.basic_info (id) on delete cascade,
+    version int not null,
+    spec JSONB not null,
+    sheet_options JSONB not null,
+    removed_keys text[],
+    author text not null default '',
+    created_at timestamp
+    with
+        time zone not null DEFAULT now(),
+        primary key (presentation_spec_id, version)
+);
+
 insert into
     presentation_spec.basic_info (
         id,
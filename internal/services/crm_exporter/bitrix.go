@@ -6,15 +6,22 @@ import (
 	"encoding/json"
 	"errors"
 	"export-service/internal/core/ports"
+	"export-service/internal/repositories"
 	"export-service/internal/repositories/crm_company_repo"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// entityTypeId of deals in the crm.category methods
+const bitrixDealEntityTypeId = 2
+
 type BitrixClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
@@ -23,7 +30,7 @@ type BitrixClient struct {
 func NewBitrixClient(baseURL string) *BitrixClient {
 	return &BitrixClient{
 		BaseURL:    baseURL,
-		HTTPClient: &http.Client{},
+		HTTPClient: newPublicHTTPClient(),
 	}
 }
 
@@ -97,13 +104,66 @@ func (b *BitrixService) Validate(ctx *fiber.Ctx, client any) bool {
 	return err == nil
 }
 
+// Install saves the inbound webhook url of the portal, like https://portal.bitrix24.com.br/rest/1/code/.
+// the url is used as the base url of the client, so it is saved in the token column read by Authorize
 func (b *BitrixService) Install(installData any) (any, error) {
-	// TODO: Implement the method
-	return nil, nil
+	installDataMap, isMap := installData.(map[string]any)
+	if !isMap {
+		return nil, errors.New("expected install data to be a map")
+	}
+
+	webhookURL, err := getBitrixWebhookURL(installDataMap["webhook_url"])
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceId, _ := installDataMap["workspace_id"].(string)
+	userId, _ := installDataMap["user_id"].(string)
+
+	_, err = b.companyRepo.GetCompanyByWorkspaceId(context.Background(), ports.CrmCompanyQueryParams{Crm: "bitrix", WorkspaceId: workspaceId})
+	var companyNotFoundError repositories.CompanyNotFoundError
+	if !errors.As(err, &companyNotFoundError) {
+		return nil, errors.New("workspace already has an installation for bitrix")
+	}
+
+	if _, err := NewBitrixClient(webhookURL).MakeRequest("GET", "user.current", nil); err != nil {
+		return nil, fmt.Errorf("invalid bitrix webhook url: %w", err)
+	}
+
+	_, err = b.companyRepo.AddCompany(context.Background(), ports.CrmAddCompanyQueryParams{
+		Crm:         "bitrix",
+		WorkspaceId: workspaceId,
+		UserId:      userId,
+		Token:       webhookURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]bool{"installed": true}, nil
+}
+
+// getBitrixWebhookURL checks the url has the /rest/<user>/<code>/ path of bitrix webhooks and ends it with a slash
+func getBitrixWebhookURL(value any) (string, error) {
+	webhookURL, _ := value.(string)
+	parsedURL, err := url.Parse(strings.TrimSpace(webhookURL))
+	if err != nil || parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return "", errors.New("a valid https webhook_url is required to install bitrix")
+	}
+
+	parts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "rest" || parts[1] == "" || parts[2] == "" {
+		return "", errors.New("webhook_url must be a bitrix inbound webhook like https://portal.bitrix24.com/rest/1/code/")
+	}
+
+	parsedURL.Path = "/" + strings.Join(parts, "/") + "/"
+	parsedURL.RawQuery = ""
+	parsedURL.Fragment = ""
+	return parsedURL.String(), nil
 }
 
 func (b *BitrixService) OAuthCallback(ctx *fiber.Ctx, params ...any) (any, error) {
-	panic("unimplemented")
+	return nil, errors.New("bitrix is installed with a webhook url, oauth is not supported")
 }
 
 func (b *BitrixService) SendLead(client any, mappedStorageData map[string]any, correspondingRawData map[string]any, configs map[string]any, existingLead map[string]any) (CreatedLead, error) {
@@ -332,19 +392,205 @@ func associationExists(associations []Association, objectType string, crmId any)
 	return false
 }
 
+// listAll follows the next offset of bitrix list methods, getItems reads the items of each page result
+func (bc *BitrixClient) listAll(method string, params map[string]any, getItems func(result any) []any) ([]any, error) {
+	var items []any
+	start := 0
+	for {
+		body := map[string]any{"start": start}
+		for key, value := range params {
+			body[key] = value
+		}
+
+		res, err := bc.MakeRequest("POST", method, body)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, getItems(res["result"])...)
+
+		next, ok := res["next"].(float64)
+		if !ok || int(next) <= start {
+			return items, nil
+		}
+		start = int(next)
+	}
+}
+
+func getBitrixResultList(result any) []any {
+	list, _ := result.([]any)
+	return list
+}
+
+// deal pipelines are the deal categories, the stages of the default category 0 are in DEAL_STAGE
+// and the ones of the other categories in DEAL_STAGE_<id>
 func (b *BitrixService) GetPipelines(client any) ([]Pipeline, error) {
-	// TODO: Implement the method
-	return nil, nil
+	bitrixClient, ok := client.(*BitrixClient)
+	if !ok {
+		return nil, errors.New("invalid Bitrix client")
+	}
+
+	categories, err := bitrixClient.listAll("crm.category.list", map[string]any{"entityTypeId": bitrixDealEntityTypeId}, func(result any) []any {
+		resultMap, _ := result.(map[string]any)
+		return getBitrixResultList(resultMap["categories"])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pipelines []Pipeline
+	for _, value := range categories {
+		category, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		categoryId := fmt.Sprint(category["id"])
+		entityId := "DEAL_STAGE"
+		if categoryId != "0" {
+			entityId += "_" + categoryId
+		}
+
+		statuses, err := bitrixClient.listAll("crm.status.list", map[string]any{
+			"filter": map[string]any{"ENTITY_ID": entityId},
+			"order":  map[string]any{"SORT": "ASC"},
+		}, getBitrixResultList)
+		if err != nil {
+			return nil, err
+		}
+
+		var stages []Stage
+		for _, statusValue := range statuses {
+			status, ok := statusValue.(map[string]any)
+			if !ok {
+				continue
+			}
+			stages = append(stages, Stage{
+				Id:   safeString(status, "STATUS_ID"),
+				Name: safeString(status, "NAME"),
+			})
+		}
+
+		pipelines = append(pipelines, Pipeline{
+			Id:     categoryId,
+			Name:   safeString(category, "name"),
+			Stages: stages,
+		})
+	}
+
+	return pipelines, nil
+}
+
+// getBitrixFields reads crm.<objectType>.fields, read only fields can't be sent and are left out
+func getBitrixFields(client *BitrixClient, objectType string) ([]CrmField, error) {
+	res, err := client.MakeRequest("POST", "crm."+objectType+".fields", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, _ := res["result"].(map[string]any)
+	ids := make([]string, 0, len(fields))
+	for id := range fields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var builtFields []CrmField
+	for _, id := range ids {
+		field, ok := fields[id].(map[string]any)
+		if !ok {
+			continue
+		}
+		if readOnly, _ := field["isReadOnly"].(bool); readOnly {
+			continue
+		}
+
+		// campos customizados (UF_) têm o nome em formLabel
+		label := safeString(field, "formLabel")
+		if label == "" {
+			label = safeString(field, "title")
+		}
+
+		var fieldOptions []FieldOptions
+		items, _ := field["items"].([]any)
+		for _, itemValue := range items {
+			item, ok := itemValue.(map[string]any)
+			if !ok {
+				continue
+			}
+			fieldOptions = append(fieldOptions, FieldOptions{
+				Id:    fmt.Sprint(item["ID"]),
+				Label: safeString(item, "VALUE"),
+			})
+		}
+
+		required, _ := field["isRequired"].(bool)
+		builtFields = append(builtFields, CrmField{
+			Id:       id,
+			Label:    label,
+			Type:     safeString(field, "type"),
+			Required: &required,
+			Options:  &fieldOptions,
+		})
+	}
+
+	return builtFields, nil
 }
 
 func (b *BitrixService) GetFields(client any) (CrmFields, error) {
-	// TODO: Implement the method
-	return CrmFields{}, nil
+	bitrixClient, ok := client.(*BitrixClient)
+	if !ok {
+		return CrmFields{}, errors.New("invalid Bitrix client")
+	}
+
+	dealFields, err := getBitrixFields(bitrixClient, "deal")
+	if err != nil {
+		return CrmFields{}, err
+	}
+	companyFields, err := getBitrixFields(bitrixClient, "company")
+	if err != nil {
+		return CrmFields{}, err
+	}
+	contactFields, err := getBitrixFields(bitrixClient, "contact")
+	if err != nil {
+		return CrmFields{}, err
+	}
+
+	return CrmFields{
+		Deals:     &dealFields,
+		Companies: &companyFields,
+		Contacts:  &contactFields,
+	}, nil
 }
 
 func (b *BitrixService) GetOwners(client any) ([]Owner, error) {
-	// TODO: Implement the method
-	return nil, nil
+	bitrixClient, ok := client.(*BitrixClient)
+	if !ok {
+		return nil, errors.New("invalid Bitrix client")
+	}
+
+	users, err := bitrixClient.listAll("user.get", map[string]any{"filter": map[string]any{"ACTIVE": true}}, getBitrixResultList)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []Owner
+	for _, value := range users {
+		user, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		name := strings.TrimSpace(safeString(user, "NAME") + " " + safeString(user, "LAST_NAME"))
+		if name == "" {
+			name = safeString(user, "EMAIL")
+		}
+		owners = append(owners, Owner{
+			Id:   fmt.Sprint(user["ID"]),
+			Name: name,
+		})
+	}
+
+	return owners, nil
 }
 
 func processBitrixCompany(client *BitrixClient, company any, existingLead, rawData map[string]any, ownerId string, overwriteData bool) (*ObjectStatus, error) {
@@ -0,0 +1,124 @@
+package crm_exporter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newBitrixTestServer answers like the rest api of a portal, user.get has two pages
+func newBitrixTestServer(t *testing.T) *crmTestServer {
+	return newCrmTestServer(t, func(request crmRequest) (int, any) {
+		filter, _ := request.Body["filter"].(map[string]any)
+
+		switch request.Path {
+		case "/rest/1/code/crm.category.list":
+			return http.StatusOK, map[string]any{"result": map[string]any{"categories": []any{
+				map[string]any{"id": 0, "name": "Geral"},
+				map[string]any{"id": 3, "name": "Parcerias"},
+			}}}
+		case "/rest/1/code/crm.status.list":
+			stages := map[string][]any{
+				"DEAL_STAGE":   {map[string]any{"STATUS_ID": "NEW", "NAME": "Novo"}, map[string]any{"STATUS_ID": "WON", "NAME": "Ganho"}},
+				"DEAL_STAGE_3": {map[string]any{"STATUS_ID": "C3:NEW", "NAME": "Contato"}},
+			}
+			entityId, _ := filter["ENTITY_ID"].(string)
+			return http.StatusOK, map[string]any{"result": stages[entityId]}
+		case "/rest/1/code/user.get":
+			if request.Body["start"] == float64(0) {
+				return http.StatusOK, map[string]any{"result": []any{map[string]any{"ID": "1", "NAME": "Fulano", "LAST_NAME": "Silva"}}, "next": 1}
+			}
+			return http.StatusOK, map[string]any{"result": []any{map[string]any{"ID": "2", "EMAIL": "beltrano@teste.com"}}}
+		case "/rest/1/code/crm.deal.fields", "/rest/1/code/crm.company.fields", "/rest/1/code/crm.contact.fields":
+			return http.StatusOK, map[string]any{"result": map[string]any{
+				"TITLE": map[string]any{"type": "string", "title": "Nome", "isRequired": true},
+				"ID":    map[string]any{"type": "integer", "title": "ID", "isReadOnly": true},
+				"UF_CRM_1": map[string]any{"type": "enumeration", "title": "UF_CRM_1", "formLabel": "Porte", "items": []any{
+					map[string]any{"ID": "10", "VALUE": "Pequeno"},
+				}},
+			}}
+		}
+		return http.StatusNotFound, nil
+	})
+}
+
+func TestBitrixGetPipelines(t *testing.T) {
+	t.Parallel()
+
+	server := newBitrixTestServer(t)
+	client := NewBitrixClient(server.URL + "/rest/1/code/")
+	client.HTTPClient = server.Client()
+
+	pipelines, err := NewBitrixService(nil).GetPipelines(client)
+	require.NoError(t, err)
+	require.Equal(t, float64(bitrixDealEntityTypeId), server.Requests()[0].Body["entityTypeId"])
+	require.Equal(t, []Pipeline{
+		{Id: "0", Name: "Geral", Stages: []Stage{{Id: "NEW", Name: "Novo"}, {Id: "WON", Name: "Ganho"}}},
+		{Id: "3", Name: "Parcerias", Stages: []Stage{{Id: "C3:NEW", Name: "Contato"}}},
+	}, pipelines)
+}
+
+func TestBitrixGetOwners(t *testing.T) {
+	t.Parallel()
+
+	server := newBitrixTestServer(t)
+	client := NewBitrixClient(server.URL + "/rest/1/code/")
+	client.HTTPClient = server.Client()
+
+	owners, err := NewBitrixService(nil).GetOwners(client)
+	require.NoError(t, err)
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	for _, request := range requests {
+		require.Equal(t, map[string]any{"ACTIVE": true}, request.Body["filter"])
+	}
+	require.Equal(t, []Owner{{Id: "1", Name: "Fulano Silva"}, {Id: "2", Name: "beltrano@teste.com"}}, owners)
+}
+
+func TestBitrixGetFields(t *testing.T) {
+	t.Parallel()
+
+	server := newBitrixTestServer(t)
+	client := NewBitrixClient(server.URL + "/rest/1/code/")
+	client.HTTPClient = server.Client()
+
+	fields, err := NewBitrixService(nil).GetFields(client)
+	require.NoError(t, err)
+
+	required, notRequired := true, false
+	var noOptions []FieldOptions
+	require.Equal(t, []CrmField{
+		{Id: "TITLE", Label: "Nome", Type: "string", Required: &required, Options: &noOptions},
+		{Id: "UF_CRM_1", Label: "Porte", Type: "enumeration", Required: &notRequired, Options: &[]FieldOptions{{Id: "10", Label: "Pequeno"}}},
+	}, *fields.Deals)
+	require.Len(t, *fields.Companies, 2)
+	require.Len(t, *fields.Contacts, 2)
+}
+
+func TestGetBitrixWebhookURL(t *testing.T) {
+	t.Parallel()
+
+	webhookURL, err := getBitrixWebhookURL(" https://teste.bitrix24.com.br/rest/1/abc123 ")
+	require.NoError(t, err)
+	require.Equal(t, "https://teste.bitrix24.com.br/rest/1/abc123/", webhookURL)
+
+	for _, invalid := range []any{nil, "", "http://teste.bitrix24.com.br/rest/1/abc123/", "https://teste.bitrix24.com.br/", "https://teste.bitrix24.com.br/rest/1/"} {
+		_, err := getBitrixWebhookURL(invalid)
+		require.Error(t, err, invalid)
+	}
+
+	_, err = NewBitrixService(nil).OAuthCallback(nil, "workspace", "user")
+	require.Error(t, err)
+}
+
+func TestBitrixBlockedAddresses(t *testing.T) {
+	t.Parallel()
+
+	server := newBitrixTestServer(t)
+
+	// o client padrão não pode chamar endereços internos com a url dada na instalação
+	_, err := NewBitrixClient(server.URL+"/rest/1/code/").MakeRequest("GET", "user.current", nil)
+	require.ErrorContains(t, err, "is not allowed")
+	require.Empty(t, server.Requests())
+}
@@ -51,8 +51,12 @@ type Crm interface {
 
 func GetCrm(crm string, co *crm_company_repo.PgCrmCompanyRepository) (Crm, bool) {
 	crms := map[string]Crm{
-		"hubspot": NewHubspotService(co),
-		"bitrix":  NewBitrixService(co),
+		"hubspot":    NewHubspotService(co),
+		"bitrix":     NewBitrixService(co),
+		"pipedrive":  NewPipedriveService(co),
+		"rdstation":  NewRDStationService(co),
+		"salesforce": NewSalesforceService(co),
+		"webhook":    NewWebhookService(co),
 	}
 
 	crmService, exists := crms[crm]
@@ -0,0 +1,197 @@
+package crm_exporter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// crmObjectSender is implemented by the crms that send a lead as a company, a deal and contacts linked to
+// each other. sendCrmLead runs the flow they share, the objectType of each method is company, deal or contact
+type crmObjectSender interface {
+	// prepare sets the owner, the stage and the fields specific to the crm in the mapped entity
+	prepare(objectType string, entity map[string]any)
+	// search returns the id of the object with the same search fields, nil when there is none
+	search(objectType string, entity map[string]any) (existingId any, searchFields map[string]any, err error)
+	create(objectType string, entity map[string]any) (crmId any, err error)
+	update(objectType string, crmId any, entity map[string]any) error
+	// link associates the objects of the lead, skipping the associations the lead already has
+	link(lead CreatedLead) (CreatedLead, error)
+}
+
+// sendCrmLead sends the objects mapped for the lead, reusing the ones already exported in existingLead
+func sendCrmLead(sender crmObjectSender, mappedStorageData, rawData, existingLead map[string]any, createDeal, overwriteData bool) (CreatedLead, error) {
+	createdLead := CreatedLead{}
+
+	if company, exists := mappedStorageData["company"]; exists {
+		companyStatus, err := processCrmObject(sender, "company", company, existingLead, rawData["company_contact_id"], overwriteData)
+		if err != nil {
+			return createdLead, err
+		}
+		createdLead.Company = companyStatus
+	}
+
+	if deal, exists := mappedStorageData["deal"]; exists && createDeal {
+		dealStatus, err := processCrmObject(sender, "deal", deal, existingLead, rawData["company_contact_id"], overwriteData)
+		if err != nil {
+			return createdLead, err
+		}
+		createdLead.Deal = dealStatus
+	}
+
+	if contact, exists := mappedStorageData["contact"]; exists {
+		contactStatus, err := processCrmObject(sender, "contact", contact, existingLead, rawData["profile_contact_id"], overwriteData)
+		if err != nil {
+			return createdLead, err
+		}
+		createdLead.Contacts = &[]ObjectStatus{*contactStatus}
+	}
+
+	if contacts, exists := mappedStorageData["contacts"]; exists {
+		contactsStatus, err := processCrmContacts(sender, contacts, existingLead, rawData, overwriteData)
+		if err != nil {
+			return createdLead, err
+		}
+		createdLead.Contacts = contactsStatus
+	}
+
+	return sender.link(createdLead)
+}
+
+// processCrmObject sends a single object of the lead, the existing lead keeps it under the objectType key
+func processCrmObject(sender crmObjectSender, objectType string, mapped any, existingLead map[string]any, drivaContactId any, overwriteData bool) (*ObjectStatus, error) {
+	exported, exists := existingLead[objectType].(map[string]any)
+	if exists && exported["crm_id"] != nil {
+		return createExistingStatus(exported), nil
+	}
+
+	mappedData, ok := mapped.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s data", objectType)
+	}
+
+	sent, err := sendCrmObject(sender, objectType, mappedData, overwriteData)
+	if err != nil {
+		return nil, err
+	}
+
+	if drivaID, ok := drivaContactId.(string); ok {
+		sent.DrivaContactId = drivaID
+	}
+	return &sent, nil
+}
+
+// processCrmContacts sends the contacts of the lead, the ones exported before are matched by their driva_contact_id
+func processCrmContacts(sender crmObjectSender, contacts any, existingLead, rawData map[string]any, overwriteData bool) (*[]ObjectStatus, error) {
+	contactsData, ok := contacts.([]any)
+	if !ok {
+		return nil, errors.New("invalid contacts data")
+	}
+
+	profilesRaw, _ := rawData["profiles"].([]any)
+
+	var statuses []ObjectStatus
+	for key, contact := range contactsData {
+		var contactRawData map[string]any
+		if key < len(profilesRaw) {
+			contactRawData, _ = profilesRaw[key].(map[string]any)
+		}
+
+		contactMap, ok := contact.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		profileContactId, _ := contactRawData["profile_contact_id"].(string)
+		if exported := findExportedContact(existingLead, profileContactId); exported != nil {
+			statuses = append(statuses, *createExistingStatus(exported))
+			continue
+		}
+
+		sentContact, err := sendCrmObject(sender, "contact", contactMap, overwriteData)
+		if err != nil {
+			return nil, err
+		}
+
+		sentContact.DrivaContactId = profileContactId
+		statuses = append(statuses, sentContact)
+	}
+
+	return &statuses, nil
+}
+
+// findExportedContact returns the contact sent in a previous export of the lead, matched by its driva_contact_id
+func findExportedContact(existingLead map[string]any, profileContactId string) map[string]any {
+	if profileContactId == "" {
+		return nil
+	}
+
+	exportedContacts, _ := existingLead["contacts"].([]any)
+	for _, value := range exportedContacts {
+		exportedContact, ok := value.(map[string]any)
+		if ok && exportedContact["driva_contact_id"] == profileContactId && exportedContact["crm_id"] != nil {
+			return exportedContact
+		}
+	}
+	return nil
+}
+
+// sendCrmObject creates the object or, when one is found with the same search fields, updates it if overwriteData is set
+func sendCrmObject(sender crmObjectSender, objectType string, mappedData map[string]any, overwriteData bool) (ObjectStatus, error) {
+	entity, err := getMappedEntity(mappedData, objectType)
+	if err != nil {
+		return ObjectStatus{Status: Failed, Message: err.Error()}, err
+	}
+	sender.prepare(objectType, entity)
+
+	existingId, searchFields, err := sender.search(objectType, entity)
+	if err != nil {
+		return ObjectStatus{
+			Status:  Failed,
+			Message: err.Error(),
+		}, err
+	}
+
+	if existingId != nil {
+		status := Skipped
+		if overwriteData {
+			if err := sender.update(objectType, existingId, entity); err != nil {
+				return ObjectStatus{
+					Status:  Failed,
+					Message: err.Error(),
+				}, err
+			}
+			status = Updated
+		}
+
+		return ObjectStatus{
+			CrmId:   existingId,
+			Status:  status,
+			Message: fmt.Sprintf("Searched fields: %s", buildSearchFields(searchFields)),
+		}, nil
+	}
+
+	crmId, err := sender.create(objectType, entity)
+	if err != nil {
+		return ObjectStatus{
+			Status:  Failed,
+			Message: err.Error(),
+		}, err
+	}
+
+	return ObjectStatus{
+		CrmId:  crmId,
+		Status: Created,
+	}, nil
+}
+
+func getMappedEntity(mappedData map[string]any, objectType string) (map[string]any, error) {
+	entity, exists := mappedData["entity"]
+	if !exists {
+		return nil, errors.New(objectType + " entity not found in mapped " + objectType + " data")
+	}
+	entityMap, isMap := entity.(map[string]any)
+	if !isMap {
+		return nil, errors.New(objectType + " entity is not a map")
+	}
+	return entityMap, nil
+}
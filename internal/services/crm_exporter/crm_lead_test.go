@@ -0,0 +1,104 @@
+package crm_exporter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeadSender finds the objects whose name is in existing and records what it sends
+type fakeLeadSender struct {
+	existing map[string]any
+	created  []string
+	updated  []string
+	linked   bool
+}
+
+func (f *fakeLeadSender) prepare(objectType string, entity map[string]any) {
+	entity["owner"] = "5"
+}
+
+func (f *fakeLeadSender) search(objectType string, entity map[string]any) (any, map[string]any, error) {
+	name, _ := entity["name"].(string)
+	return f.existing[name], map[string]any{"name": name}, nil
+}
+
+func (f *fakeLeadSender) create(objectType string, entity map[string]any) (any, error) {
+	f.created = append(f.created, fmt.Sprintf("%s %v", objectType, entity["name"]))
+	return fmt.Sprintf("%s-%d", objectType, len(f.created)), nil
+}
+
+func (f *fakeLeadSender) update(objectType string, crmId any, entity map[string]any) error {
+	f.updated = append(f.updated, fmt.Sprintf("%s %v", objectType, crmId))
+	return nil
+}
+
+func (f *fakeLeadSender) link(lead CreatedLead) (CreatedLead, error) {
+	f.linked = true
+	return lead, nil
+}
+
+func TestSendCrmLead(t *testing.T) {
+	t.Parallel()
+
+	mappedStorageData := map[string]any{
+		"company": map[string]any{"entity": map[string]any{"name": "empresa"}},
+		"deal":    map[string]any{"entity": map[string]any{"name": "deal"}},
+		"contacts": []any{
+			map[string]any{"entity": map[string]any{"name": "exportado"}},
+			map[string]any{"entity": map[string]any{"name": "existente"}},
+			map[string]any{"entity": map[string]any{"name": "novo"}},
+		},
+	}
+	rawData := map[string]any{
+		"company_contact_id": "c1",
+		"profiles": []any{
+			map[string]any{"profile_contact_id": "p1"},
+			map[string]any{"profile_contact_id": "p2"},
+			map[string]any{"profile_contact_id": "p3"},
+		},
+	}
+	existingLead := map[string]any{
+		"deal": map[string]any{"crm_id": "deal-antigo", "status": "created"},
+		"contacts": []any{
+			map[string]any{"crm_id": "contato-antigo", "status": "created", "driva_contact_id": "p1"},
+		},
+	}
+
+	t.Run("Should reuse the exported objects and skip the existing ones", func(t *testing.T) {
+		sender := &fakeLeadSender{existing: map[string]any{"existente": "contato-existente"}}
+
+		createdLead, err := sendCrmLead(sender, mappedStorageData, rawData, existingLead, true, false)
+		require.NoError(t, err)
+
+		require.Equal(t, &ObjectStatus{CrmId: "company-1", Status: Created, DrivaContactId: "c1"}, createdLead.Company)
+		require.Equal(t, &ObjectStatus{CrmId: "deal-antigo", Status: Created}, createdLead.Deal)
+		require.Equal(t, &[]ObjectStatus{
+			{CrmId: "contato-antigo", Status: Created, DrivaContactId: "p1"},
+			{CrmId: "contato-existente", Status: Skipped, Message: `Searched fields: {"name":"existente"}`, DrivaContactId: "p2"},
+			{CrmId: "contact-2", Status: Created, DrivaContactId: "p3"},
+		}, createdLead.Contacts)
+		require.Equal(t, []string{"company empresa", "contact novo"}, sender.created)
+		require.Empty(t, sender.updated)
+		require.True(t, sender.linked)
+	})
+
+	t.Run("Should update the existing objects when overwriting data", func(t *testing.T) {
+		sender := &fakeLeadSender{existing: map[string]any{"existente": "contato-existente"}}
+
+		createdLead, err := sendCrmLead(sender, mappedStorageData, rawData, existingLead, true, true)
+		require.NoError(t, err)
+		require.Equal(t, Updated, (*createdLead.Contacts)[1].Status)
+		require.Equal(t, []string{"contact contato-existente"}, sender.updated)
+	})
+
+	t.Run("Should not send the deal when create_deal is off", func(t *testing.T) {
+		sender := &fakeLeadSender{}
+
+		createdLead, err := sendCrmLead(sender, mappedStorageData, rawData, map[string]any{}, false, false)
+		require.NoError(t, err)
+		require.Nil(t, createdLead.Deal)
+		require.Len(t, *createdLead.Contacts, 3)
+	})
+}
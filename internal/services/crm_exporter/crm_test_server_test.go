@@ -0,0 +1,65 @@
+package crm_exporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// crmRequest is a request received by a crmTestServer, Index counts the requests from 1
+type crmRequest struct {
+	Index   int
+	Method  string
+	Path    string
+	Query   url.Values
+	Header  http.Header
+	RawBody []byte
+	Body    map[string]any
+}
+
+// crmTestServer records the requests it receives, the tests assert on them after the call returns
+// because a failed assertion inside the handler goroutine can't stop the test
+type crmTestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []crmRequest
+}
+
+// newCrmTestServer answers each request with the status and the json returned by respond,
+// a nil response is sent without body
+func newCrmTestServer(t *testing.T, respond func(request crmRequest) (int, any)) *crmTestServer {
+	s := &crmTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawBody, _ := io.ReadAll(r.Body)
+		request := crmRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), RawBody: rawBody}
+		_ = json.Unmarshal(rawBody, &request.Body)
+
+		s.mu.Lock()
+		request.Index = len(s.requests) + 1
+		s.requests = append(s.requests, request)
+		s.mu.Unlock()
+
+		status, response := respond(request)
+		if response == nil {
+			w.WriteHeader(status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the requests received so far, in the order they arrived
+func (s *crmTestServer) Requests() []crmRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]crmRequest(nil), s.requests...)
+}
@@ -0,0 +1,668 @@
+package crm_exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"export-service/internal/core/ports"
+	"export-service/internal/repositories"
+	"export-service/internal/repositories/crm_company_repo"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	pipedriveApiURL   = "https://api.pipedrive.com/v1/"
+	pipedriveOAuthURL = "https://oauth.pipedrive.com/oauth/"
+	pipedrivePageSize = 500
+)
+
+// PipedriveClient authenticates with the api token of the company or with an oauth access token
+type PipedriveClient struct {
+	BaseURL     string
+	ApiToken    string
+	AccessToken string
+	HTTPClient  *http.Client
+}
+
+func NewPipedriveClient(baseURL, apiToken, accessToken string) *PipedriveClient {
+	return &PipedriveClient{
+		BaseURL:     baseURL,
+		ApiToken:    apiToken,
+		AccessToken: accessToken,
+		HTTPClient:  &http.Client{},
+	}
+}
+
+func (pc *PipedriveClient) MakeRequest(method, endpoint string, body any) (map[string]any, error) {
+	requestURL, err := url.Parse(pc.BaseURL + endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if pc.ApiToken != "" {
+		query := requestURL.Query()
+		query.Set("api_token", pc.ApiToken)
+		requestURL.RawQuery = query.Encode()
+	}
+
+	var jsonBody []byte
+	if body != nil {
+		jsonBody, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, requestURL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if pc.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+pc.AccessToken)
+	}
+
+	resp, err := pc.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("pipedrive http error: %s", responseBody)
+	}
+
+	var result map[string]any
+	err = json.Unmarshal(responseBody, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	if success, ok := result["success"].(bool); ok && !success {
+		return nil, fmt.Errorf("pipedrive error: %v", result["error"])
+	}
+
+	return result, nil
+}
+
+// getAll follows the pagination of list endpoints and returns the items of every page
+func (pc *PipedriveClient) getAll(endpoint string) ([]any, error) {
+	var items []any
+	start := 0
+	for {
+		separator := "?"
+		if strings.Contains(endpoint, "?") {
+			separator = "&"
+		}
+		res, err := pc.MakeRequest("GET", fmt.Sprintf("%s%sstart=%d&limit=%d", endpoint, separator, start, pipedrivePageSize), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if data, ok := res["data"].([]any); ok {
+			items = append(items, data...)
+		}
+
+		additionalData, _ := res["additional_data"].(map[string]any)
+		pagination, _ := additionalData["pagination"].(map[string]any)
+		if more, _ := pagination["more_items_in_collection"].(bool); !more {
+			return items, nil
+		}
+		nextStart, ok := pagination["next_start"].(float64)
+		if !ok {
+			return items, nil
+		}
+		start = int(nextStart)
+	}
+}
+
+type PipedriveService struct {
+	companyRepo *crm_company_repo.PgCrmCompanyRepository
+}
+
+func NewPipedriveService(companyRepo *crm_company_repo.PgCrmCompanyRepository) *PipedriveService {
+	return &PipedriveService{companyRepo: companyRepo}
+}
+
+// Authorize uses the api token saved on install, companies installed with oauth get a new access token
+func (p *PipedriveService) Authorize(ctx context.Context, workspaceId string) (any, error) {
+	company, err := p.companyRepo.GetCompanyByWorkspaceId(ctx, ports.CrmCompanyQueryParams{Crm: "pipedrive", WorkspaceId: workspaceId})
+	if err != nil {
+		return nil, err
+	}
+
+	if company.Token.String != "" {
+		return NewPipedriveClient(pipedriveApiURL, company.Token.String, ""), nil
+	}
+
+	if company.RefreshToken.String == "" {
+		return nil, errors.New("api token or refresh token not found for " + workspaceId)
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "refresh_token")
+	formData.Set("refresh_token", company.RefreshToken.String)
+	tokens, err := requestPipedriveToken(formData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.companyRepo.UpdateTokens(ctx, ports.CrmUpdateTokensQueryParams{
+		Id:           company.Id,
+		RefreshToken: tokens.RefreshToken,
+		AccessToken:  tokens.AccessToken,
+		ExpiresIn:    strconv.Itoa(tokens.ExpiresIn),
+		InstanceUrl:  tokens.ApiDomain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Authenticated pipedrive for workspaceId: %s", company.WorkspaceId.String)
+
+	return NewPipedriveClient(getPipedriveApiURL(tokens.ApiDomain, company.InstanceUrl.String), "", tokens.AccessToken), nil
+}
+
+func (p *PipedriveService) Validate(c *fiber.Ctx, client any) bool {
+	pipedriveClient, ok := client.(*PipedriveClient)
+	if !ok {
+		return false
+	}
+
+	_, err := pipedriveClient.MakeRequest("GET", "users/me", nil)
+	return err == nil
+}
+
+// Install saves the api_token in the install data after checking it, without a token it returns the oauth url
+func (p *PipedriveService) Install(installData any) (any, error) {
+	installDataMap, isMap := installData.(map[string]any)
+	if !isMap {
+		return nil, errors.New("expected install data to be a map")
+	}
+
+	workspaceId, _ := installDataMap["workspace_id"].(string)
+	userId, _ := installDataMap["user_id"].(string)
+
+	if apiToken, _ := installDataMap["api_token"].(string); apiToken != "" {
+		_, err := p.companyRepo.GetCompanyByWorkspaceId(context.Background(), ports.CrmCompanyQueryParams{Crm: "pipedrive", WorkspaceId: workspaceId})
+		var companyNotFoundError repositories.CompanyNotFoundError
+		if !errors.As(err, &companyNotFoundError) {
+			return nil, errors.New("workspace already has an installation for pipedrive")
+		}
+
+		if _, err := NewPipedriveClient(pipedriveApiURL, apiToken, "").MakeRequest("GET", "users/me", nil); err != nil {
+			return nil, fmt.Errorf("invalid pipedrive api token: %w", err)
+		}
+
+		_, err = p.companyRepo.AddCompany(context.Background(), ports.CrmAddCompanyQueryParams{
+			Crm:         "pipedrive",
+			WorkspaceId: workspaceId,
+			UserId:      userId,
+			Token:       apiToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"installed": true}, nil
+	}
+
+	state := fmt.Sprintf("%s|%s", workspaceId, userId)
+	authURL := fmt.Sprintf("%sauthorize?client_id=%s&redirect_uri=%s&state=%s", pipedriveOAuthURL, url.QueryEscape(os.Getenv("PIPEDRIVE_CLIENT_ID")), url.QueryEscape(os.Getenv("PIPEDRIVE_REDIRECT_URI")), url.QueryEscape(state))
+
+	return map[string]string{"url": authURL}, nil
+}
+
+func (p *PipedriveService) OAuthCallback(c *fiber.Ctx, params ...any) (any, error) {
+	if len(params) != 2 {
+		return nil, errors.New("expected 2 params in oauth callback")
+	}
+
+	workspaceId, _ := params[0].(string)
+	userId, _ := params[1].(string)
+
+	_, err := p.companyRepo.GetCompanyByWorkspaceId(c.Context(), ports.CrmCompanyQueryParams{Crm: "pipedrive", WorkspaceId: workspaceId})
+	var companyNotFoundError repositories.CompanyNotFoundError
+	if !errors.As(err, &companyNotFoundError) {
+		return nil, errors.New("workspace already has an installation for pipedrive")
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "authorization_code")
+	formData.Set("code", c.Query("code"))
+	formData.Set("redirect_uri", os.Getenv("PIPEDRIVE_REDIRECT_URI"))
+	tokens, err := requestPipedriveToken(formData)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.companyRepo.AddCompany(context.Background(), ports.CrmAddCompanyQueryParams{
+		Crm:          "pipedrive",
+		WorkspaceId:  workspaceId,
+		UserId:       userId,
+		RefreshToken: tokens.RefreshToken,
+		AccessToken:  tokens.AccessToken,
+		ExpiresIn:    strconv.Itoa(tokens.ExpiresIn),
+		InstanceUrl:  tokens.ApiDomain,
+	})
+	return nil, err
+}
+
+type pipedriveTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	ApiDomain    string `json:"api_domain"`
+}
+
+func requestPipedriveToken(formData url.Values) (pipedriveTokens, error) {
+	req, err := http.NewRequest("POST", pipedriveOAuthURL+"token", strings.NewReader(formData.Encode()))
+	if err != nil {
+		return pipedriveTokens{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(os.Getenv("PIPEDRIVE_CLIENT_ID"), os.Getenv("PIPEDRIVE_CLIENT_SECRET"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return pipedriveTokens{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return pipedriveTokens{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return pipedriveTokens{}, fmt.Errorf("pipedrive oauth error: %s", body)
+	}
+
+	var tokens pipedriveTokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return pipedriveTokens{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return tokens, nil
+}
+
+// getPipedriveApiURL uses the domain of the company account, returned with the oauth tokens
+func getPipedriveApiURL(apiDomains ...string) string {
+	for _, apiDomain := range apiDomains {
+		if apiDomain != "" {
+			return strings.TrimSuffix(apiDomain, "/") + "/api/v1/"
+		}
+	}
+	return pipedriveApiURL
+}
+
+func (p *PipedriveService) SendLead(client any, mappedStorageData map[string]any, correspondingRawData map[string]any, configs map[string]any, existingLead map[string]any) (CreatedLead, error) {
+	pipedriveClient, ok := client.(*PipedriveClient)
+	if !ok {
+		return CreatedLead{}, errors.New("invalid Pipedrive client")
+	}
+
+	ownerId, err := getConfigValue[string](configs, "owner_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	stageId, err := getConfigValue[string](configs, "stage_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	pipelineId, err := getConfigValue[string](configs, "pipeline_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	createDeal, _ := configs["create_deal"].(bool)
+	overwriteData, _ := configs["overwrite_data"].(bool)
+
+	sender := pipedriveLead{client: pipedriveClient, ownerId: ownerId, pipelineId: pipelineId, stageId: stageId}
+	return sendCrmLead(sender, mappedStorageData, correspondingRawData, existingLead, createDeal, overwriteData)
+}
+
+// pipedriveLead sends the objects of a lead with the configs of the export
+type pipedriveLead struct {
+	client     *PipedriveClient
+	ownerId    string
+	pipelineId string
+	stageId    string
+}
+
+var _ crmObjectSender = pipedriveLead{}
+
+var pipedriveEndpoints = map[string]string{
+	"company": "organizations",
+	"deal":    "deals",
+	"contact": "persons",
+}
+
+func (p pipedriveLead) prepare(objectType string, entity map[string]any) {
+	if objectType != "deal" {
+		setPipedriveOwner(entity, "owner_id", p.ownerId)
+		return
+	}
+
+	entity["pipeline_id"] = getPipedriveId(p.pipelineId)
+	entity["stage_id"] = getPipedriveId(p.stageId)
+	setPipedriveOwner(entity, "user_id", p.ownerId)
+}
+
+// persons are searched by the first email, email may be a string or a list of strings or {"value": ...} maps
+func (p pipedriveLead) search(objectType string, entity map[string]any) (any, map[string]any, error) {
+	searchField, searchValue := "name", entity["name"]
+	switch objectType {
+	case "deal":
+		searchField, searchValue = "title", entity["title"]
+	case "contact":
+		searchField, searchValue = "email", getFirstPipedriveEmail(entity["email"])
+	}
+
+	existingId, err := searchForExistingPipedriveObject(p.client, pipedriveEndpoints[objectType], searchField, searchValue)
+	return existingId, map[string]any{searchField: searchValue}, err
+}
+
+func (p pipedriveLead) create(objectType string, entity map[string]any) (any, error) {
+	created, err := p.client.MakeRequest("POST", pipedriveEndpoints[objectType], entity)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := created["data"].(map[string]any)
+	return data["id"], nil
+}
+
+func (p pipedriveLead) update(objectType string, crmId any, entity map[string]any) error {
+	_, err := p.client.MakeRequest("PUT", fmt.Sprintf("%s/%v", pipedriveEndpoints[objectType], crmId), entity)
+	return err
+}
+
+func (p pipedriveLead) link(lead CreatedLead) (CreatedLead, error) {
+	return createPipedriveAssociations(p.client, lead)
+}
+
+// pipedrive links organizations, persons and deals through their org_id and person_id fields,
+// the other persons of a deal are added as participants
+func createPipedriveAssociations(client *PipedriveClient, lead CreatedLead) (CreatedLead, error) {
+
+	if lead.Deal != nil && lead.Company != nil {
+		if !associationExists(lead.Deal.Associations, "company", lead.Company.CrmId) {
+			_, err := client.MakeRequest("PUT", fmt.Sprintf("deals/%v", lead.Deal.CrmId), map[string]any{"org_id": lead.Company.CrmId})
+			if err != nil {
+				return lead, err
+			}
+
+			lead.Deal.Associations = append(lead.Deal.Associations, Association{
+				ObjectType: "company",
+				CrmId:      lead.Company.CrmId,
+			})
+		}
+	}
+
+	if lead.Deal != nil && lead.Contacts != nil && len(*lead.Contacts) > 0 {
+		var contactIds []any
+		for _, contact := range *lead.Contacts {
+			contactIds = append(contactIds, contact.CrmId)
+		}
+
+		if !associationExists(lead.Deal.Associations, "contacts", contactIds) {
+			_, err := client.MakeRequest("PUT", fmt.Sprintf("deals/%v", lead.Deal.CrmId), map[string]any{"person_id": contactIds[0]})
+			if err != nil {
+				return lead, err
+			}
+
+			for _, contactId := range contactIds[1:] {
+				_, err := client.MakeRequest("POST", fmt.Sprintf("deals/%v/participants", lead.Deal.CrmId), map[string]any{"person_id": contactId})
+				if err != nil {
+					return lead, err
+				}
+			}
+
+			lead.Deal.Associations = append(lead.Deal.Associations, Association{
+				ObjectType: "contacts",
+				CrmId:      contactIds,
+			})
+		}
+	}
+
+	if lead.Company != nil && lead.Contacts != nil {
+		for i := range *lead.Contacts {
+			contact := &(*lead.Contacts)[i]
+
+			if !associationExists(contact.Associations, "company", lead.Company.CrmId) {
+				_, err := client.MakeRequest("PUT", fmt.Sprintf("persons/%v", contact.CrmId), map[string]any{"org_id": lead.Company.CrmId})
+				if err != nil {
+					return lead, err
+				}
+
+				contact.Associations = append(contact.Associations, Association{
+					ObjectType: "company",
+					CrmId:      lead.Company.CrmId,
+				})
+			}
+		}
+	}
+
+	return lead, nil
+}
+
+func getFirstPipedriveEmail(email any) any {
+	switch v := email.(type) {
+	case string:
+		return v
+	case []any:
+		for _, item := range v {
+			switch emailItem := item.(type) {
+			case string:
+				if emailItem != "" {
+					return emailItem
+				}
+			case map[string]any:
+				if value, ok := emailItem["value"].(string); ok && value != "" {
+					return value
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func searchForExistingPipedriveObject(client *PipedriveClient, objectType string, searchField string, searchValue any) (any, error) {
+	term, ok := searchValue.(string)
+	if !ok || len(term) < 2 {
+		return nil, nil
+	}
+
+	query := url.Values{}
+	query.Set("term", term)
+	query.Set("fields", searchField)
+	query.Set("exact_match", "true")
+	query.Set("limit", "1")
+
+	res, err := client.MakeRequest("GET", objectType+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := res["data"].(map[string]any)
+	items, _ := data["items"].([]any)
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	item, _ := items[0].(map[string]any)["item"].(map[string]any)
+	return item["id"], nil
+}
+
+// setPipedriveOwner keeps the owner of the account that owns the token when no owner is chosen
+func setPipedriveOwner(entity map[string]any, field string, ownerId string) {
+	if ownerId == "" || ownerId == "nenhum" {
+		return
+	}
+	entity[field] = getPipedriveId(ownerId)
+}
+
+// ids come as strings in the configs but pipedrive expects numbers
+func getPipedriveId(id string) any {
+	if number, err := strconv.Atoi(id); err == nil {
+		return number
+	}
+	return id
+}
+
+func (p *PipedriveService) GetPipelines(client any) ([]Pipeline, error) {
+	pipedriveClient, ok := client.(*PipedriveClient)
+	if !ok {
+		return nil, errors.New("invalid Pipedrive client")
+	}
+
+	pipelinesData, err := pipedriveClient.getAll("pipelines")
+	if err != nil {
+		return nil, err
+	}
+
+	stagesData, err := pipedriveClient.getAll("stages")
+	if err != nil {
+		return nil, err
+	}
+
+	stagesByPipeline := make(map[string][]Stage)
+	for _, value := range stagesData {
+		stage, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		pipelineId := fmt.Sprint(stage["pipeline_id"])
+		stagesByPipeline[pipelineId] = append(stagesByPipeline[pipelineId], Stage{
+			Id:   fmt.Sprint(stage["id"]),
+			Name: safeString(stage, "name"),
+		})
+	}
+
+	var pipelines []Pipeline
+	for _, value := range pipelinesData {
+		pipeline, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		pipelineId := fmt.Sprint(pipeline["id"])
+		pipelines = append(pipelines, Pipeline{
+			Id:     pipelineId,
+			Name:   safeString(pipeline, "name"),
+			Stages: stagesByPipeline[pipelineId],
+		})
+	}
+
+	return pipelines, nil
+}
+
+func buildPipedriveFields(fieldsData []any) []CrmField {
+	var builtFields []CrmField
+	for _, value := range fieldsData {
+		field, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var fieldOptions []FieldOptions
+		if options, ok := field["options"].([]any); ok {
+			for _, optionValue := range options {
+				option, ok := optionValue.(map[string]any)
+				if !ok {
+					continue
+				}
+				fieldOptions = append(fieldOptions, FieldOptions{
+					Id:    fmt.Sprint(option["id"]),
+					Label: safeString(option, "label"),
+				})
+			}
+		}
+
+		crmField := CrmField{
+			Id:      field["key"],
+			Label:   safeString(field, "name"),
+			Type:    safeString(field, "field_type"),
+			Options: &fieldOptions,
+		}
+		if required, ok := field["mandatory_flag"].(bool); ok {
+			crmField.Required = &required
+		}
+		builtFields = append(builtFields, crmField)
+	}
+
+	return builtFields
+}
+
+func (p *PipedriveService) GetFields(client any) (CrmFields, error) {
+	pipedriveClient, ok := client.(*PipedriveClient)
+	if !ok {
+		return CrmFields{}, errors.New("invalid Pipedrive client")
+	}
+
+	dealFields, err := pipedriveClient.getAll("dealFields")
+	if err != nil {
+		return CrmFields{}, err
+	}
+	companyFields, err := pipedriveClient.getAll("organizationFields")
+	if err != nil {
+		return CrmFields{}, err
+	}
+	contactFields, err := pipedriveClient.getAll("personFields")
+	if err != nil {
+		return CrmFields{}, err
+	}
+
+	builtDealFields := buildPipedriveFields(dealFields)
+	builtCompanyFields := buildPipedriveFields(companyFields)
+	builtContactFields := buildPipedriveFields(contactFields)
+
+	return CrmFields{
+		Deals:     &builtDealFields,
+		Companies: &builtCompanyFields,
+		Contacts:  &builtContactFields,
+	}, nil
+}
+
+func (p *PipedriveService) GetOwners(client any) ([]Owner, error) {
+	pipedriveClient, ok := client.(*PipedriveClient)
+	if !ok {
+		return nil, errors.New("invalid Pipedrive client")
+	}
+
+	res, err := pipedriveClient.MakeRequest("GET", "users", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []Owner
+	users, _ := res["data"].([]any)
+	for _, value := range users {
+		user, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if active, ok := user["active_flag"].(bool); ok && !active {
+			continue
+		}
+		owners = append(owners, Owner{
+			Id:   fmt.Sprint(user["id"]),
+			Name: safeString(user, "name"),
+		})
+	}
+
+	return owners, nil
+}
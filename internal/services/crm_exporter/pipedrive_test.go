@@ -0,0 +1,149 @@
+package crm_exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newPipedriveTestServer answers like the pipedrive api, the persons search finds the contact with email existente@teste.com
+func newPipedriveTestServer(t *testing.T) *crmTestServer {
+	return newCrmTestServer(t, func(request crmRequest) (int, any) {
+		switch {
+		case strings.HasSuffix(request.Path, "/search"):
+			items := []any{}
+			if request.Query.Get("term") == "existente@teste.com" {
+				items = append(items, map[string]any{"item": map[string]any{"id": 7}})
+			}
+			return http.StatusOK, map[string]any{"success": true, "data": map[string]any{"items": items}}
+		case request.Path == "/pipelines":
+			return http.StatusOK, map[string]any{"success": true, "data": []any{
+				map[string]any{"id": 1, "name": "Vendas"},
+			}}
+		case request.Path == "/stages":
+			return http.StatusOK, map[string]any{"success": true, "data": []any{
+				map[string]any{"id": 10, "name": "Qualificado", "pipeline_id": 1},
+				map[string]any{"id": 11, "name": "Proposta", "pipeline_id": 1},
+			}}
+		}
+		return http.StatusOK, map[string]any{"success": true, "data": map[string]any{"id": 100 + request.Index}}
+	})
+}
+
+// requirePipedriveToken checks that every request was authenticated with the api token
+func requirePipedriveToken(t *testing.T, requests []crmRequest) {
+	for _, request := range requests {
+		require.Equal(t, "token", request.Query.Get("api_token"), request.Path)
+	}
+}
+
+func TestPipedriveSendLead(t *testing.T) {
+	t.Parallel()
+
+	server := newPipedriveTestServer(t)
+	client := NewPipedriveClient(server.URL+"/", "token", "")
+
+	lead := map[string]any{
+		"company": map[string]any{
+			"entity": map[string]any{
+				"name": "empresa teste",
+			},
+		},
+		"deal": map[string]any{
+			"entity": map[string]any{
+				"title": "deal teste",
+			},
+		},
+		"contacts": []any{
+			map[string]any{
+				"entity": map[string]any{
+					"name":  "contato teste 1",
+					"email": "novo@teste.com",
+				},
+			},
+			map[string]any{
+				"entity": map[string]any{
+					"name":  "contato teste 2",
+					"email": "existente@teste.com",
+				},
+			},
+		},
+	}
+	configs := map[string]any{
+		"owner_id":       "5",
+		"pipeline_id":    "1",
+		"stage_id":       "10",
+		"create_deal":    true,
+		"overwrite_data": false,
+	}
+
+	createdLead, err := NewPipedriveService(nil).SendLead(client, lead, map[string]any{}, configs, map[string]any{})
+	require.NoError(t, err)
+
+	require.Equal(t, Created, createdLead.Company.Status)
+	require.Equal(t, Created, createdLead.Deal.Status)
+	require.Len(t, *createdLead.Contacts, 2)
+	require.Equal(t, Created, (*createdLead.Contacts)[0].Status)
+	require.Equal(t, Skipped, (*createdLead.Contacts)[1].Status)
+	require.Equal(t, float64(7), (*createdLead.Contacts)[1].CrmId)
+
+	companyId := createdLead.Company.CrmId
+	dealId := createdLead.Deal.CrmId
+	firstContactId := (*createdLead.Contacts)[0].CrmId
+
+	requests := server.Requests()
+	requirePipedriveToken(t, requests)
+
+	var createdDeal, dealCompany, dealPerson, participant map[string]any
+	personsWithCompany := 0
+	for _, request := range requests {
+		switch {
+		case request.Method == "POST" && request.Path == "/deals":
+			createdDeal = request.Body
+		case request.Method == "PUT" && request.Path == "/deals/"+jsonNumber(dealId) && request.Body["org_id"] != nil:
+			dealCompany = request.Body
+		case request.Method == "PUT" && request.Path == "/deals/"+jsonNumber(dealId) && request.Body["person_id"] != nil:
+			dealPerson = request.Body
+		case request.Method == "POST" && request.Path == "/deals/"+jsonNumber(dealId)+"/participants":
+			participant = request.Body
+		case request.Method == "PUT" && strings.HasPrefix(request.Path, "/persons/"):
+			require.Equal(t, companyId, request.Body["org_id"])
+			personsWithCompany++
+		}
+	}
+
+	require.Equal(t, map[string]any{"title": "deal teste", "pipeline_id": float64(1), "stage_id": float64(10), "user_id": float64(5)}, createdDeal)
+	require.Equal(t, companyId, dealCompany["org_id"])
+	require.Equal(t, firstContactId, dealPerson["person_id"])
+	require.Equal(t, float64(7), participant["person_id"])
+	require.Equal(t, 2, personsWithCompany)
+}
+
+func TestPipedriveGetPipelines(t *testing.T) {
+	t.Parallel()
+
+	server := newPipedriveTestServer(t)
+	client := NewPipedriveClient(server.URL+"/", "token", "")
+
+	pipelines, err := NewPipedriveService(nil).GetPipelines(client)
+	require.NoError(t, err)
+	requirePipedriveToken(t, server.Requests())
+	require.Equal(t, []Pipeline{
+		{
+			Id:   "1",
+			Name: "Vendas",
+			Stages: []Stage{
+				{Id: "10", Name: "Qualificado"},
+				{Id: "11", Name: "Proposta"},
+			},
+		},
+	}, pipelines)
+}
+
+func jsonNumber(value any) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
@@ -0,0 +1,561 @@
+package crm_exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"export-service/internal/core/ports"
+	"export-service/internal/repositories"
+	"export-service/internal/repositories/crm_company_repo"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	rdStationApiURL = "https://crm.rdstation.com/api/v1/"
+	// custom fields are mapped with this prefix before their id, SendLead moves them to the <object>_custom_fields list
+	rdStationCustomFieldPrefix = "custom_field:"
+)
+
+type RDStationClient struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func NewRDStationClient(baseURL, token string) *RDStationClient {
+	return &RDStationClient{
+		BaseURL:    baseURL,
+		Token:      token,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// MakeRequest decodes the response in result, some endpoints answer with lists instead of objects
+func (rc *RDStationClient) MakeRequest(method, endpoint string, body any, result any) error {
+	requestURL, err := url.Parse(rc.BaseURL + endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+	query := requestURL.Query()
+	query.Set("token", rc.Token)
+	requestURL.RawQuery = query.Encode()
+
+	var jsonBody []byte
+	if body != nil {
+		jsonBody, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, requestURL.String(), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := rc.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("rdstation http error: %s", responseBody)
+	}
+
+	if result == nil || len(responseBody) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		return fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	return nil
+}
+
+type RDStationService struct {
+	companyRepo *crm_company_repo.PgCrmCompanyRepository
+}
+
+func NewRDStationService(companyRepo *crm_company_repo.PgCrmCompanyRepository) *RDStationService {
+	return &RDStationService{companyRepo: companyRepo}
+}
+
+func (r *RDStationService) Authorize(ctx context.Context, workspaceId string) (any, error) {
+	company, err := r.companyRepo.GetCompanyByWorkspaceId(ctx, ports.CrmCompanyQueryParams{Crm: "rdstation", WorkspaceId: workspaceId})
+	if err != nil {
+		return nil, err
+	}
+
+	if company.Token.String == "" {
+		return nil, errors.New("token not found for " + workspaceId)
+	}
+
+	return NewRDStationClient(rdStationApiURL, company.Token.String), nil
+}
+
+func (r *RDStationService) Validate(c *fiber.Ctx, client any) bool {
+	rdClient, ok := client.(*RDStationClient)
+	if !ok {
+		return false
+	}
+
+	return rdClient.MakeRequest("GET", "token/check", nil, nil) == nil
+}
+
+// Install saves the token of the account, rd station crm has no oauth for it
+func (r *RDStationService) Install(installData any) (any, error) {
+	installDataMap, isMap := installData.(map[string]any)
+	if !isMap {
+		return nil, errors.New("expected install data to be a map")
+	}
+
+	token, _ := installDataMap["token"].(string)
+	if token == "" {
+		return nil, errors.New("token is required to install rdstation")
+	}
+
+	workspaceId, _ := installDataMap["workspace_id"].(string)
+	userId, _ := installDataMap["user_id"].(string)
+
+	_, err := r.companyRepo.GetCompanyByWorkspaceId(context.Background(), ports.CrmCompanyQueryParams{Crm: "rdstation", WorkspaceId: workspaceId})
+	var companyNotFoundError repositories.CompanyNotFoundError
+	if !errors.As(err, &companyNotFoundError) {
+		return nil, errors.New("workspace already has an installation for rdstation")
+	}
+
+	if err := NewRDStationClient(rdStationApiURL, token).MakeRequest("GET", "token/check", nil, nil); err != nil {
+		return nil, fmt.Errorf("invalid rdstation token: %w", err)
+	}
+
+	_, err = r.companyRepo.AddCompany(context.Background(), ports.CrmAddCompanyQueryParams{
+		Crm:         "rdstation",
+		WorkspaceId: workspaceId,
+		UserId:      userId,
+		Token:       token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]bool{"installed": true}, nil
+}
+
+func (r *RDStationService) OAuthCallback(c *fiber.Ctx, params ...any) (any, error) {
+	return nil, errors.New("rdstation is installed with a token, oauth is not supported")
+}
+
+func (r *RDStationService) SendLead(client any, mappedStorageData map[string]any, correspondingRawData map[string]any, configs map[string]any, existingLead map[string]any) (CreatedLead, error) {
+	rdClient, ok := client.(*RDStationClient)
+	if !ok {
+		return CreatedLead{}, errors.New("invalid RD Station client")
+	}
+
+	ownerId, err := getConfigValue[string](configs, "owner_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	stageId, err := getConfigValue[string](configs, "stage_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	createDeal, _ := configs["create_deal"].(bool)
+	overwriteData, _ := configs["overwrite_data"].(bool)
+
+	sender := rdStationLead{client: rdClient, ownerId: ownerId, stageId: stageId}
+	return sendCrmLead(sender, mappedStorageData, correspondingRawData, existingLead, createDeal, overwriteData)
+}
+
+// rdStationLead sends the objects of a lead with the configs of the export
+type rdStationLead struct {
+	client  *RDStationClient
+	ownerId string
+	stageId string
+}
+
+var _ crmObjectSender = rdStationLead{}
+
+// rdStationObjects has the endpoint and the key the entity is sent under for each object of the lead
+var rdStationObjects = map[string]struct{ endpoint, key string }{
+	"company": {endpoint: "organizations", key: "organization"},
+	"deal":    {endpoint: "deals", key: "deal"},
+	"contact": {endpoint: "contacts", key: "contact"},
+}
+
+// contacts have no owner in rd station, a plain email in their mapping is sent as the emails list rd station expects
+func (r rdStationLead) prepare(objectType string, entity map[string]any) {
+	setRDStationCustomFields(entity, rdStationObjects[objectType].key)
+
+	switch objectType {
+	case "deal":
+		entity["deal_stage_id"] = r.stageId
+		setRDStationOwner(entity, r.ownerId)
+	case "company":
+		setRDStationOwner(entity, r.ownerId)
+	case "contact":
+		if email, ok := entity["email"].(string); ok {
+			delete(entity, "email")
+			if _, exists := entity["emails"]; !exists && email != "" {
+				entity["emails"] = []any{map[string]any{"email": email}}
+			}
+		}
+	}
+}
+
+// contacts are searched by the first email, organizations and deals by name
+func (r rdStationLead) search(objectType string, entity map[string]any) (any, map[string]any, error) {
+	endpoint := rdStationObjects[objectType].endpoint
+
+	if objectType == "contact" {
+		var email string
+		if emails, ok := entity["emails"].([]any); ok && len(emails) > 0 {
+			if firstEmail, ok := emails[0].(map[string]any); ok {
+				email, _ = firstEmail["email"].(string)
+			}
+		}
+
+		existingId, err := searchForExistingRDStationObject(r.client, endpoint, url.Values{"email": {email}}, "", email)
+		return existingId, map[string]any{"email": email}, err
+	}
+
+	name, _ := entity["name"].(string)
+	filters := url.Values{"q": {name}}
+	if objectType == "deal" {
+		filters = url.Values{"name": {name}, "exact_name": {"true"}}
+	}
+
+	existingId, err := searchForExistingRDStationObject(r.client, endpoint, filters, "name", name)
+	return existingId, map[string]any{"name": name}, err
+}
+
+func (r rdStationLead) create(objectType string, entity map[string]any) (any, error) {
+	object := rdStationObjects[objectType]
+
+	var created map[string]any
+	if err := r.client.MakeRequest("POST", object.endpoint, map[string]any{object.key: entity}, &created); err != nil {
+		return nil, err
+	}
+	return getRDStationId(created), nil
+}
+
+func (r rdStationLead) update(objectType string, crmId any, entity map[string]any) error {
+	object := rdStationObjects[objectType]
+	return r.client.MakeRequest("PUT", fmt.Sprintf("%s/%v", object.endpoint, crmId), map[string]any{object.key: entity}, nil)
+}
+
+func (r rdStationLead) link(lead CreatedLead) (CreatedLead, error) {
+	return createRDStationAssociations(r.client, lead)
+}
+
+// deals keep their organization, contacts keep their organization_id and the deal_ids they take part in
+func createRDStationAssociations(client *RDStationClient, lead CreatedLead) (CreatedLead, error) {
+
+	if lead.Deal != nil && lead.Company != nil {
+		if !associationExists(lead.Deal.Associations, "company", lead.Company.CrmId) {
+			err := client.MakeRequest("PUT", fmt.Sprintf("deals/%v", lead.Deal.CrmId), map[string]any{
+				"deal":         map[string]any{},
+				"organization": map[string]any{"_id": lead.Company.CrmId},
+			}, nil)
+			if err != nil {
+				return lead, err
+			}
+
+			lead.Deal.Associations = append(lead.Deal.Associations, Association{
+				ObjectType: "company",
+				CrmId:      lead.Company.CrmId,
+			})
+		}
+	}
+
+	if lead.Deal != nil && lead.Contacts != nil && len(*lead.Contacts) > 0 {
+		var contactIds []any
+		for _, contact := range *lead.Contacts {
+			contactIds = append(contactIds, contact.CrmId)
+		}
+
+		if !associationExists(lead.Deal.Associations, "contacts", contactIds) {
+			for _, contactId := range contactIds {
+				if err := addRDStationContactDeal(client, contactId, lead.Deal.CrmId); err != nil {
+					return lead, err
+				}
+			}
+
+			lead.Deal.Associations = append(lead.Deal.Associations, Association{
+				ObjectType: "contacts",
+				CrmId:      contactIds,
+			})
+		}
+	}
+
+	if lead.Company != nil && lead.Contacts != nil {
+		for i := range *lead.Contacts {
+			contact := &(*lead.Contacts)[i]
+
+			if !associationExists(contact.Associations, "company", lead.Company.CrmId) {
+				err := client.MakeRequest("PUT", fmt.Sprintf("contacts/%v", contact.CrmId), map[string]any{
+					"contact": map[string]any{"organization_id": lead.Company.CrmId},
+				}, nil)
+				if err != nil {
+					return lead, err
+				}
+
+				contact.Associations = append(contact.Associations, Association{
+					ObjectType: "company",
+					CrmId:      lead.Company.CrmId,
+				})
+			}
+		}
+	}
+
+	return lead, nil
+}
+
+// addRDStationContactDeal keeps the deals the contact already has, deal_ids replaces the whole list
+func addRDStationContactDeal(client *RDStationClient, contactId, dealId any) error {
+	var contact map[string]any
+	if err := client.MakeRequest("GET", fmt.Sprintf("contacts/%v", contactId), nil, &contact); err != nil {
+		return err
+	}
+
+	dealIds, _ := contact["deal_ids"].([]any)
+	for _, id := range dealIds {
+		if fmt.Sprint(id) == fmt.Sprint(dealId) {
+			return nil
+		}
+	}
+	dealIds = append(dealIds, dealId)
+
+	return client.MakeRequest("PUT", fmt.Sprintf("contacts/%v", contactId), map[string]any{
+		"contact": map[string]any{"deal_ids": dealIds},
+	}, nil)
+}
+
+// searchForExistingRDStationObject lists objects with the filters, the filters of rd station are not exact
+// so the first result with the same matchField is used. without matchField the first result is used
+func searchForExistingRDStationObject(client *RDStationClient, endpoint string, filters url.Values, matchField string, searchValue string) (any, error) {
+	if searchValue == "" {
+		return nil, nil
+	}
+
+	var res map[string]any
+	if err := client.MakeRequest("GET", endpoint+"?"+filters.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+
+	items, _ := res[endpoint].([]any)
+	for _, value := range items {
+		item, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if matchField == "" || strings.EqualFold(safeString(item, matchField), searchValue) {
+			return getRDStationId(item), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// rd station returns the id both in id and in _id depending on the endpoint
+func getRDStationId(item map[string]any) any {
+	if id, exists := item["id"]; exists && id != nil {
+		return id
+	}
+	return item["_id"]
+}
+
+func setRDStationOwner(entity map[string]any, ownerId string) {
+	if ownerId == "" || ownerId == "nenhum" {
+		return
+	}
+	entity["user_id"] = ownerId
+}
+
+// setRDStationCustomFields moves the custom_field:<id> keys of the entity to the <objectType>_custom_fields list
+func setRDStationCustomFields(entity map[string]any, objectType string) {
+	var customFields []any
+	for key, value := range entity {
+		customFieldId, isCustomField := strings.CutPrefix(key, rdStationCustomFieldPrefix)
+		if !isCustomField {
+			continue
+		}
+		customFields = append(customFields, map[string]any{
+			"custom_field_id": customFieldId,
+			"value":           value,
+		})
+		delete(entity, key)
+	}
+
+	if len(customFields) > 0 {
+		entity[objectType+"_custom_fields"] = customFields
+	}
+}
+
+func (r *RDStationService) GetPipelines(client any) ([]Pipeline, error) {
+	rdClient, ok := client.(*RDStationClient)
+	if !ok {
+		return nil, errors.New("invalid RD Station client")
+	}
+
+	var pipelinesData []map[string]any
+	if err := rdClient.MakeRequest("GET", "deal_pipelines", nil, &pipelinesData); err != nil {
+		return nil, err
+	}
+
+	var pipelines []Pipeline
+	for _, pipeline := range pipelinesData {
+		var stages []Stage
+		stagesData, _ := pipeline["deal_stages"].([]any)
+		for _, value := range stagesData {
+			stage, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			stages = append(stages, Stage{
+				Id:   fmt.Sprint(getRDStationId(stage)),
+				Name: safeString(stage, "name"),
+			})
+		}
+
+		pipelines = append(pipelines, Pipeline{
+			Id:     fmt.Sprint(getRDStationId(pipeline)),
+			Name:   safeString(pipeline, "name"),
+			Stages: stages,
+		})
+	}
+
+	return pipelines, nil
+}
+
+// getRDStationCustomFields lists the custom fields of deal, contact or organization
+func getRDStationCustomFields(client *RDStationClient, option string) ([]CrmField, error) {
+	var res map[string]any
+	if err := client.MakeRequest("GET", "custom_fields?option="+option, nil, &res); err != nil {
+		return nil, err
+	}
+
+	var builtFields []CrmField
+	customFields, _ := res["custom_fields"].([]any)
+	for _, value := range customFields {
+		field, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var fieldOptions []FieldOptions
+		options, _ := field["opts"].([]any)
+		for _, option := range options {
+			fieldOptions = append(fieldOptions, FieldOptions{
+				Id:    fmt.Sprint(option),
+				Label: fmt.Sprint(option),
+			})
+		}
+
+		crmField := CrmField{
+			Id:      rdStationCustomFieldPrefix + fmt.Sprint(getRDStationId(field)),
+			Label:   safeString(field, "label"),
+			Type:    safeString(field, "type"),
+			Options: &fieldOptions,
+		}
+		if required, ok := field["required"].(bool); ok {
+			crmField.Required = &required
+		}
+		builtFields = append(builtFields, crmField)
+	}
+
+	return builtFields, nil
+}
+
+func newRDStationField(id, label string, required bool) CrmField {
+	return CrmField{
+		Id:       id,
+		Label:    label,
+		Type:     "string",
+		Required: &required,
+	}
+}
+
+func (r *RDStationService) GetFields(client any) (CrmFields, error) {
+	rdClient, ok := client.(*RDStationClient)
+	if !ok {
+		return CrmFields{}, errors.New("invalid RD Station client")
+	}
+
+	// campos fixos da api, os customizados vêm de custom_fields
+	dealFields := []CrmField{
+		newRDStationField("name", "Nome", true),
+		newRDStationField("amount_montly", "Valor mensal", false),
+		newRDStationField("amount_unique", "Valor único", false),
+	}
+	companyFields := []CrmField{
+		newRDStationField("name", "Nome", true),
+		newRDStationField("url", "Site", false),
+		newRDStationField("resume", "Resumo", false),
+	}
+	contactFields := []CrmField{
+		newRDStationField("name", "Nome", true),
+		newRDStationField("title", "Cargo", false),
+		newRDStationField("email", "Email", false),
+		newRDStationField("notes", "Observações", false),
+	}
+
+	for option, fields := range map[string]*[]CrmField{"deal": &dealFields, "organization": &companyFields, "contact": &contactFields} {
+		customFields, err := getRDStationCustomFields(rdClient, option)
+		if err != nil {
+			return CrmFields{}, err
+		}
+		*fields = append(*fields, customFields...)
+	}
+
+	return CrmFields{
+		Deals:     &dealFields,
+		Companies: &companyFields,
+		Contacts:  &contactFields,
+	}, nil
+}
+
+func (r *RDStationService) GetOwners(client any) ([]Owner, error) {
+	rdClient, ok := client.(*RDStationClient)
+	if !ok {
+		return nil, errors.New("invalid RD Station client")
+	}
+
+	var res map[string]any
+	if err := rdClient.MakeRequest("GET", "users", nil, &res); err != nil {
+		return nil, err
+	}
+
+	var owners []Owner
+	users, _ := res["users"].([]any)
+	for _, value := range users {
+		user, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if active, ok := user["active"].(bool); ok && !active {
+			continue
+		}
+		owners = append(owners, Owner{
+			Id:   fmt.Sprint(getRDStationId(user)),
+			Name: safeString(user, "name"),
+		})
+	}
+
+	return owners, nil
+}
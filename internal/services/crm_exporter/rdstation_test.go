@@ -0,0 +1,149 @@
+package crm_exporter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newRDStationTestServer answers like the rd station crm api, the organizations search finds Empresa Existente
+// and the contact c1 already takes part in the deal d0
+func newRDStationTestServer(t *testing.T) *crmTestServer {
+	return newCrmTestServer(t, func(request crmRequest) (int, any) {
+		switch {
+		case request.Method == "GET" && request.Path == "/organizations":
+			organizations := []any{map[string]any{"id": "o9", "name": "Empresa Existente Filial"}}
+			if request.Query.Get("q") == "empresa existente" {
+				organizations = append(organizations, map[string]any{"id": "o1", "name": "Empresa Existente"})
+			}
+			return http.StatusOK, map[string]any{"organizations": organizations}
+		case request.Method == "GET" && (request.Path == "/deals" || request.Path == "/contacts"):
+			return http.StatusOK, map[string]any{strings.TrimPrefix(request.Path, "/"): []any{}}
+		case request.Method == "GET" && strings.HasPrefix(request.Path, "/contacts/"):
+			return http.StatusOK, map[string]any{"id": strings.TrimPrefix(request.Path, "/contacts/"), "deal_ids": []any{"d0"}}
+		case request.Path == "/deal_pipelines":
+			return http.StatusOK, []any{
+				map[string]any{"id": "p1", "name": "Funil padrão", "deal_stages": []any{
+					map[string]any{"id": "s1", "name": "Sem contato"},
+					map[string]any{"id": "s2", "name": "Proposta"},
+				}},
+			}
+		case request.Method == "POST":
+			return http.StatusOK, map[string]any{"_id": fmt.Sprintf("%c%d", request.Path[1], request.Index)}
+		}
+		return http.StatusOK, map[string]any{}
+	})
+}
+
+// requireRDStationToken checks that every request was authenticated with the token
+func requireRDStationToken(t *testing.T, requests []crmRequest) {
+	for _, request := range requests {
+		require.Equal(t, "token", request.Query.Get("token"), request.Path)
+	}
+}
+
+func TestRDStationSendLead(t *testing.T) {
+	t.Parallel()
+
+	server := newRDStationTestServer(t)
+	client := NewRDStationClient(server.URL+"/", "token")
+
+	lead := map[string]any{
+		"company": map[string]any{
+			"entity": map[string]any{
+				"name": "empresa existente",
+			},
+		},
+		"deal": map[string]any{
+			"entity": map[string]any{
+				"name":                      "deal teste",
+				"custom_field:5f1a2b3c4d5e": "PR",
+			},
+		},
+		"contact": map[string]any{
+			"entity": map[string]any{
+				"name":  "contato teste",
+				"email": "fulano@teste.com",
+			},
+		},
+	}
+	configs := map[string]any{
+		"owner_id":       "u1",
+		"pipeline_id":    "p1",
+		"stage_id":       "s2",
+		"create_deal":    true,
+		"overwrite_data": false,
+	}
+
+	createdLead, err := NewRDStationService(nil).SendLead(client, lead, map[string]any{}, configs, map[string]any{})
+	require.NoError(t, err)
+
+	require.Equal(t, Skipped, createdLead.Company.Status)
+	require.Equal(t, "o1", createdLead.Company.CrmId)
+	require.Equal(t, Created, createdLead.Deal.Status)
+	require.Len(t, *createdLead.Contacts, 1)
+	require.Equal(t, Created, (*createdLead.Contacts)[0].Status)
+
+	dealId := createdLead.Deal.CrmId.(string)
+	contactId := (*createdLead.Contacts)[0].CrmId.(string)
+
+	requests := server.Requests()
+	requireRDStationToken(t, requests)
+
+	var createdDeal, createdContact, dealUpdate map[string]any
+	var contactUpdates []map[string]any
+	for _, request := range requests {
+		switch {
+		case request.Method == "POST" && request.Path == "/deals":
+			createdDeal = request.Body["deal"].(map[string]any)
+		case request.Method == "POST" && request.Path == "/contacts":
+			createdContact = request.Body["contact"].(map[string]any)
+		case request.Method == "PUT" && request.Path == "/deals/"+dealId:
+			dealUpdate = request.Body
+		case request.Method == "PUT" && request.Path == "/contacts/"+contactId:
+			contactUpdates = append(contactUpdates, request.Body["contact"].(map[string]any))
+		}
+	}
+
+	require.Equal(t, map[string]any{
+		"name":          "deal teste",
+		"deal_stage_id": "s2",
+		"user_id":       "u1",
+		"deal_custom_fields": []any{
+			map[string]any{"custom_field_id": "5f1a2b3c4d5e", "value": "PR"},
+		},
+	}, createdDeal)
+	require.Equal(t, map[string]any{
+		"name":   "contato teste",
+		"emails": []any{map[string]any{"email": "fulano@teste.com"}},
+	}, createdContact)
+	require.Equal(t, map[string]any{"_id": "o1"}, dealUpdate["organization"])
+	require.Equal(t, []map[string]any{
+		{"deal_ids": []any{"d0", dealId}},
+		{"organization_id": "o1"},
+	}, contactUpdates)
+}
+
+func TestRDStationGetPipelines(t *testing.T) {
+	t.Parallel()
+
+	server := newRDStationTestServer(t)
+	client := NewRDStationClient(server.URL+"/", "token")
+
+	pipelines, err := NewRDStationService(nil).GetPipelines(client)
+	require.NoError(t, err)
+	requireRDStationToken(t, server.Requests())
+	require.Equal(t, []Pipeline{
+		{
+			Id:   "p1",
+			Name: "Funil padrão",
+			Stages: []Stage{
+				{Id: "s1", Name: "Sem contato"},
+				{Id: "s2", Name: "Proposta"},
+			},
+		},
+	}, pipelines)
+}
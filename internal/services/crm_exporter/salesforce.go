@@ -0,0 +1,606 @@
+package crm_exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"export-service/internal/core/ports"
+	"export-service/internal/repositories"
+	"export-service/internal/repositories/crm_company_repo"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	salesforceLoginURL = "https://login.salesforce.com"
+	salesforceApiPath  = "/services/data/v59.0/"
+	// salesforce has no pipelines, the opportunity stages are listed as a single one
+	salesforcePipelineId = "Opportunity"
+	// dias até o fechamento quando a oportunidade não tem CloseDate
+	salesforceCloseDays = 30
+)
+
+type SalesforceClient struct {
+	InstanceUrl string
+	AccessToken string
+	HTTPClient  *http.Client
+}
+
+func NewSalesforceClient(instanceUrl, accessToken string) *SalesforceClient {
+	return &SalesforceClient{
+		InstanceUrl: strings.TrimSuffix(instanceUrl, "/"),
+		AccessToken: accessToken,
+		HTTPClient:  &http.Client{},
+	}
+}
+
+// MakeRequest calls the rest api of the instance, endpoints are relative to the api version path
+func (sc *SalesforceClient) MakeRequest(method, endpoint string, body any) (map[string]any, error) {
+	return sc.do(method, sc.InstanceUrl+salesforceApiPath+endpoint, body)
+}
+
+func (sc *SalesforceClient) do(method, requestURL string, body any) (map[string]any, error) {
+	var jsonBody []byte
+	if body != nil {
+		var err error
+		jsonBody, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, requestURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sc.AccessToken)
+
+	resp, err := sc.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("salesforce http error: %s", responseBody)
+	}
+
+	// updates answer with 204 and no body
+	if len(responseBody) == 0 {
+		return nil, nil
+	}
+
+	var result map[string]any
+	err = json.Unmarshal(responseBody, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	return result, nil
+}
+
+// query runs a soql query and follows nextRecordsUrl until every record is read
+func (sc *SalesforceClient) query(soql string) ([]map[string]any, error) {
+	res, err := sc.MakeRequest("GET", "query?q="+url.QueryEscape(soql), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]any
+	for {
+		pageRecords, _ := res["records"].([]any)
+		for _, value := range pageRecords {
+			if record, ok := value.(map[string]any); ok {
+				records = append(records, record)
+			}
+		}
+
+		nextRecordsUrl, _ := res["nextRecordsUrl"].(string)
+		if done, _ := res["done"].(bool); done || nextRecordsUrl == "" {
+			return records, nil
+		}
+
+		res, err = sc.do("GET", sc.InstanceUrl+nextRecordsUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+type SalesforceService struct {
+	companyRepo *crm_company_repo.PgCrmCompanyRepository
+}
+
+func NewSalesforceService(companyRepo *crm_company_repo.PgCrmCompanyRepository) *SalesforceService {
+	return &SalesforceService{companyRepo: companyRepo}
+}
+
+func getSalesforceLoginURL() string {
+	if loginURL := os.Getenv("SALESFORCE_LOGIN_URL"); loginURL != "" {
+		return strings.TrimSuffix(loginURL, "/")
+	}
+	return salesforceLoginURL
+}
+
+type salesforceTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	InstanceUrl  string `json:"instance_url"`
+}
+
+func requestSalesforceToken(formData url.Values) (salesforceTokens, error) {
+	formData.Set("client_id", os.Getenv("SALESFORCE_CLIENT_ID"))
+	formData.Set("client_secret", os.Getenv("SALESFORCE_CLIENT_SECRET"))
+
+	resp, err := http.PostForm(getSalesforceLoginURL()+"/services/oauth2/token", formData)
+	if err != nil {
+		return salesforceTokens{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return salesforceTokens{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return salesforceTokens{}, fmt.Errorf("salesforce oauth error: %s", body)
+	}
+
+	var tokens salesforceTokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return salesforceTokens{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *SalesforceService) Authorize(ctx context.Context, workspaceId string) (any, error) {
+	company, err := s.companyRepo.GetCompanyByWorkspaceId(ctx, ports.CrmCompanyQueryParams{Crm: "salesforce", WorkspaceId: workspaceId})
+	if err != nil {
+		return nil, err
+	}
+
+	if company.RefreshToken.String == "" {
+		return nil, errors.New("refresh token not found for " + workspaceId)
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "refresh_token")
+	formData.Set("refresh_token", company.RefreshToken.String)
+	tokens, err := requestSalesforceToken(formData)
+	if err != nil {
+		return nil, err
+	}
+
+	// salesforce keeps the same refresh token, it only comes back on the first exchange
+	err = s.companyRepo.UpdateTokens(ctx, ports.CrmUpdateTokensQueryParams{
+		Id:           company.Id,
+		RefreshToken: tokens.RefreshToken,
+		AccessToken:  tokens.AccessToken,
+		InstanceUrl:  tokens.InstanceUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Authenticated salesforce for workspaceId: %s", company.WorkspaceId.String)
+
+	instanceUrl := tokens.InstanceUrl
+	if instanceUrl == "" {
+		instanceUrl = company.InstanceUrl.String
+	}
+	return NewSalesforceClient(instanceUrl, tokens.AccessToken), nil
+}
+
+func (s *SalesforceService) Validate(c *fiber.Ctx, client any) bool {
+	salesforceClient, ok := client.(*SalesforceClient)
+	if !ok {
+		return false
+	}
+
+	_, err := salesforceClient.MakeRequest("GET", "limits", nil)
+	return err == nil
+}
+
+func (s *SalesforceService) Install(installData any) (any, error) {
+	installDataMap, isMap := installData.(map[string]any)
+	if !isMap {
+		return nil, errors.New("expected install data to be a map")
+	}
+	state := fmt.Sprintf("%s|%s", installDataMap["workspace_id"], installDataMap["user_id"])
+
+	authURL := fmt.Sprintf("%s/services/oauth2/authorize?response_type=code&client_id=%s&redirect_uri=%s&scope=%s&state=%s",
+		getSalesforceLoginURL(),
+		url.QueryEscape(os.Getenv("SALESFORCE_CLIENT_ID")),
+		url.QueryEscape(os.Getenv("SALESFORCE_REDIRECT_URI")),
+		url.QueryEscape("api refresh_token"),
+		url.QueryEscape(state),
+	)
+
+	return map[string]string{"url": authURL}, nil
+}
+
+func (s *SalesforceService) OAuthCallback(c *fiber.Ctx, params ...any) (any, error) {
+	if len(params) != 2 {
+		return nil, errors.New("expected 2 params in oauth callback")
+	}
+
+	workspaceId, _ := params[0].(string)
+	userId, _ := params[1].(string)
+
+	_, err := s.companyRepo.GetCompanyByWorkspaceId(c.Context(), ports.CrmCompanyQueryParams{Crm: "salesforce", WorkspaceId: workspaceId})
+	var companyNotFoundError repositories.CompanyNotFoundError
+	if !errors.As(err, &companyNotFoundError) {
+		return nil, errors.New("workspace already has an installation for salesforce")
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "authorization_code")
+	formData.Set("code", c.Query("code"))
+	formData.Set("redirect_uri", os.Getenv("SALESFORCE_REDIRECT_URI"))
+	tokens, err := requestSalesforceToken(formData)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.companyRepo.AddCompany(context.Background(), ports.CrmAddCompanyQueryParams{
+		Crm:          "salesforce",
+		WorkspaceId:  workspaceId,
+		UserId:       userId,
+		RefreshToken: tokens.RefreshToken,
+		AccessToken:  tokens.AccessToken,
+		InstanceUrl:  tokens.InstanceUrl,
+	})
+	return nil, err
+}
+
+func (s *SalesforceService) SendLead(client any, mappedStorageData map[string]any, correspondingRawData map[string]any, configs map[string]any, existingLead map[string]any) (CreatedLead, error) {
+	salesforceClient, ok := client.(*SalesforceClient)
+	if !ok {
+		return CreatedLead{}, errors.New("invalid Salesforce client")
+	}
+
+	ownerId, err := getConfigValue[string](configs, "owner_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	stageId, err := getConfigValue[string](configs, "stage_id")
+	if err != nil {
+		return CreatedLead{}, err
+	}
+
+	createDeal, _ := configs["create_deal"].(bool)
+	overwriteData, _ := configs["overwrite_data"].(bool)
+
+	sender := salesforceLead{client: salesforceClient, ownerId: ownerId, stageId: stageId}
+	return sendCrmLead(sender, mappedStorageData, correspondingRawData, existingLead, createDeal, overwriteData)
+}
+
+// salesforceLead sends the objects of a lead with the configs of the export
+type salesforceLead struct {
+	client  *SalesforceClient
+	ownerId string
+	stageId string
+}
+
+var _ crmObjectSender = salesforceLead{}
+
+var salesforceObjectTypes = map[string]string{
+	"company": "Account",
+	"deal":    "Opportunity",
+	"contact": "Contact",
+}
+
+func (s salesforceLead) prepare(objectType string, entity map[string]any) {
+	setSalesforceOwner(entity, s.ownerId)
+	if objectType != "deal" {
+		return
+	}
+
+	entity["StageName"] = s.stageId
+	if _, exists := entity["CloseDate"]; !exists {
+		entity["CloseDate"] = time.Now().AddDate(0, 0, salesforceCloseDays).Format(time.DateOnly)
+	}
+}
+
+// contacts are searched by Email, accounts and opportunities by Name
+func (s salesforceLead) search(objectType string, entity map[string]any) (any, map[string]any, error) {
+	searchField := "Name"
+	if objectType == "contact" {
+		searchField = "Email"
+	}
+
+	existingId, err := searchForExistingSalesforceObject(s.client, salesforceObjectTypes[objectType], searchField, entity[searchField])
+	return existingId, map[string]any{searchField: entity[searchField]}, err
+}
+
+func (s salesforceLead) create(objectType string, entity map[string]any) (any, error) {
+	created, err := s.client.MakeRequest("POST", "sobjects/"+salesforceObjectTypes[objectType], entity)
+	if err != nil {
+		return nil, err
+	}
+	return created["id"], nil
+}
+
+func (s salesforceLead) update(objectType string, crmId any, entity map[string]any) error {
+	_, err := s.client.MakeRequest("PATCH", fmt.Sprintf("sobjects/%s/%v", salesforceObjectTypes[objectType], crmId), entity)
+	return err
+}
+
+func (s salesforceLead) link(lead CreatedLead) (CreatedLead, error) {
+	return createSalesforceAssociations(s.client, lead)
+}
+
+// accounts are linked through the AccountId of opportunities and contacts,
+// contacts are linked to opportunities with contact roles, the first one as primary
+func createSalesforceAssociations(client *SalesforceClient, lead CreatedLead) (CreatedLead, error) {
+
+	if lead.Deal != nil && lead.Company != nil {
+		if !associationExists(lead.Deal.Associations, "company", lead.Company.CrmId) {
+			_, err := client.MakeRequest("PATCH", fmt.Sprintf("sobjects/Opportunity/%v", lead.Deal.CrmId), map[string]any{"AccountId": lead.Company.CrmId})
+			if err != nil {
+				return lead, err
+			}
+
+			lead.Deal.Associations = append(lead.Deal.Associations, Association{
+				ObjectType: "company",
+				CrmId:      lead.Company.CrmId,
+			})
+		}
+	}
+
+	if lead.Deal != nil && lead.Contacts != nil && len(*lead.Contacts) > 0 {
+		var contactIds []any
+		for _, contact := range *lead.Contacts {
+			contactIds = append(contactIds, contact.CrmId)
+		}
+
+		if !associationExists(lead.Deal.Associations, "contacts", contactIds) {
+			// oportunidades já exportadas podem ter os papéis criados numa exportação anterior
+			existingRoles, err := getSalesforceContactRoles(client, lead.Deal.CrmId)
+			if err != nil {
+				return lead, err
+			}
+
+			hasPrimary := len(existingRoles) > 0
+			for _, contactId := range contactIds {
+				if existingRoles[fmt.Sprint(contactId)] {
+					continue
+				}
+
+				_, err := client.MakeRequest("POST", "sobjects/OpportunityContactRole", map[string]any{
+					"OpportunityId": lead.Deal.CrmId,
+					"ContactId":     contactId,
+					"IsPrimary":     !hasPrimary,
+				})
+				if err != nil {
+					return lead, err
+				}
+				hasPrimary = true
+			}
+
+			lead.Deal.Associations = append(lead.Deal.Associations, Association{
+				ObjectType: "contacts",
+				CrmId:      contactIds,
+			})
+		}
+	}
+
+	if lead.Company != nil && lead.Contacts != nil {
+		for i := range *lead.Contacts {
+			contact := &(*lead.Contacts)[i]
+
+			if !associationExists(contact.Associations, "company", lead.Company.CrmId) {
+				_, err := client.MakeRequest("PATCH", fmt.Sprintf("sobjects/Contact/%v", contact.CrmId), map[string]any{"AccountId": lead.Company.CrmId})
+				if err != nil {
+					return lead, err
+				}
+
+				contact.Associations = append(contact.Associations, Association{
+					ObjectType: "company",
+					CrmId:      lead.Company.CrmId,
+				})
+			}
+		}
+	}
+
+	return lead, nil
+}
+
+// getSalesforceContactRoles returns the ids of the contacts that already have a role in the opportunity
+func getSalesforceContactRoles(client *SalesforceClient, opportunityId any) (map[string]bool, error) {
+	records, err := client.query(fmt.Sprintf("SELECT ContactId FROM OpportunityContactRole WHERE OpportunityId = '%s'", escapeSOQL(fmt.Sprint(opportunityId))))
+	if err != nil {
+		return nil, err
+	}
+
+	contactIds := make(map[string]bool, len(records))
+	for _, record := range records {
+		contactIds[fmt.Sprint(record["ContactId"])] = true
+	}
+	return contactIds, nil
+}
+
+func searchForExistingSalesforceObject(client *SalesforceClient, objectType string, searchField string, searchValue any) (any, error) {
+	value, ok := searchValue.(string)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	records, err := client.query(fmt.Sprintf("SELECT Id FROM %s WHERE %s = '%s' LIMIT 1", objectType, searchField, escapeSOQL(value)))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	return records[0]["Id"], nil
+}
+
+// escapeSOQL escapes the values used inside quotes in soql queries
+func escapeSOQL(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+func setSalesforceOwner(entity map[string]any, ownerId string) {
+	if ownerId == "" || ownerId == "nenhum" {
+		return
+	}
+	entity["OwnerId"] = ownerId
+}
+
+func (s *SalesforceService) describe(client any, objectType string) (map[string]any, error) {
+	salesforceClient, ok := client.(*SalesforceClient)
+	if !ok {
+		return nil, errors.New("invalid Salesforce client")
+	}
+
+	return salesforceClient.MakeRequest("GET", fmt.Sprintf("sobjects/%s/describe", objectType), nil)
+}
+
+func (s *SalesforceService) GetPipelines(client any) ([]Pipeline, error) {
+	description, err := s.describe(client, "Opportunity")
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []Stage
+	fields, _ := description["fields"].([]any)
+	for _, value := range fields {
+		field, ok := value.(map[string]any)
+		if !ok || field["name"] != "StageName" {
+			continue
+		}
+
+		for _, picklistValue := range getActivePicklistValues(field) {
+			stages = append(stages, Stage{
+				Id:   safeString(picklistValue, "value"),
+				Name: safeString(picklistValue, "label"),
+			})
+		}
+	}
+
+	return []Pipeline{
+		{
+			Id:     salesforcePipelineId,
+			Name:   safeString(description, "label"),
+			Stages: stages,
+		},
+	}, nil
+}
+
+func getActivePicklistValues(field map[string]any) []map[string]any {
+	var picklistValues []map[string]any
+	values, _ := field["picklistValues"].([]any)
+	for _, value := range values {
+		picklistValue, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if active, ok := picklistValue["active"].(bool); ok && !active {
+			continue
+		}
+		picklistValues = append(picklistValues, picklistValue)
+	}
+	return picklistValues
+}
+
+// buildSalesforceFields keeps only the fields that can be set on create
+func buildSalesforceFields(description map[string]any) []CrmField {
+	var builtFields []CrmField
+	fields, _ := description["fields"].([]any)
+	for _, value := range fields {
+		field, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if createable, _ := field["createable"].(bool); !createable {
+			continue
+		}
+
+		var fieldOptions []FieldOptions
+		for _, picklistValue := range getActivePicklistValues(field) {
+			fieldOptions = append(fieldOptions, FieldOptions{
+				Id:    safeString(picklistValue, "value"),
+				Label: safeString(picklistValue, "label"),
+			})
+		}
+
+		nillable, _ := field["nillable"].(bool)
+		defaultedOnCreate, _ := field["defaultedOnCreate"].(bool)
+		required := !nillable && !defaultedOnCreate
+
+		builtFields = append(builtFields, CrmField{
+			Id:       field["name"],
+			Label:    safeString(field, "label"),
+			Type:     safeString(field, "type"),
+			Required: &required,
+			Options:  &fieldOptions,
+		})
+	}
+
+	return builtFields
+}
+
+func (s *SalesforceService) GetFields(client any) (CrmFields, error) {
+	dealDescription, err := s.describe(client, "Opportunity")
+	if err != nil {
+		return CrmFields{}, err
+	}
+	companyDescription, err := s.describe(client, "Account")
+	if err != nil {
+		return CrmFields{}, err
+	}
+	contactDescription, err := s.describe(client, "Contact")
+	if err != nil {
+		return CrmFields{}, err
+	}
+
+	builtDealFields := buildSalesforceFields(dealDescription)
+	builtCompanyFields := buildSalesforceFields(companyDescription)
+	builtContactFields := buildSalesforceFields(contactDescription)
+
+	return CrmFields{
+		Deals:     &builtDealFields,
+		Companies: &builtCompanyFields,
+		Contacts:  &builtContactFields,
+	}, nil
+}
+
+func (s *SalesforceService) GetOwners(client any) ([]Owner, error) {
+	salesforceClient, ok := client.(*SalesforceClient)
+	if !ok {
+		return nil, errors.New("invalid Salesforce client")
+	}
+
+	records, err := salesforceClient.query("SELECT Id, Name FROM User WHERE IsActive = true AND UserType = 'Standard' ORDER BY Name")
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []Owner
+	for _, record := range records {
+		owners = append(owners, Owner{
+			Id:   safeString(record, "Id"),
+			Name: safeString(record, "Name"),
+		})
+	}
+
+	return owners, nil
+}
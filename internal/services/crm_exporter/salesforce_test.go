@@ -0,0 +1,207 @@
+package crm_exporter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newSalesforceTestServer answers like the salesforce rest api, the soql lookup finds the account named empresa d'existente
+func newSalesforceTestServer(t *testing.T) *crmTestServer {
+	return newCrmTestServer(t, func(request crmRequest) (int, any) {
+		path := strings.TrimPrefix(request.Path, salesforceApiPath)
+
+		switch {
+		case path == "query":
+			records := []any{}
+			switch request.Query.Get("q") {
+			case `SELECT Id FROM Account WHERE Name = 'empresa d\'existente' LIMIT 1`:
+				records = append(records, map[string]any{"Id": "001EXISTENTE"})
+			case `SELECT Id FROM Contact WHERE Email = 'existente@teste.com' LIMIT 1`:
+				records = append(records, map[string]any{"Id": "003EXISTENTE"})
+			case `SELECT ContactId FROM OpportunityContactRole WHERE OpportunityId = '006EXISTENTE'`:
+				records = append(records, map[string]any{"ContactId": "003EXISTENTE"})
+			}
+			return http.StatusOK, map[string]any{"done": true, "records": records}
+		case path == "sobjects/Opportunity/describe":
+			return http.StatusOK, map[string]any{"label": "Opportunity", "fields": []any{
+				map[string]any{"name": "StageName", "picklistValues": []any{
+					map[string]any{"value": "Prospecting", "label": "Prospecção", "active": true},
+					map[string]any{"value": "Old", "label": "Antigo", "active": false},
+					map[string]any{"value": "Closed Won", "label": "Ganho", "active": true},
+				}},
+			}}
+		case request.Method == "PATCH":
+			return http.StatusNoContent, nil
+		}
+		return http.StatusOK, map[string]any{"id": fmt.Sprintf("00%d", request.Index), "success": true}
+	})
+}
+
+// getSalesforceRequests returns the requests with the path relative to the api version,
+// after checking that every one was authenticated with the access token
+func getSalesforceRequests(t *testing.T, server *crmTestServer) []crmRequest {
+	requests := server.Requests()
+	for i, request := range requests {
+		require.Equal(t, "Bearer token", request.Header.Get("Authorization"), request.Path)
+		requests[i].Path = strings.TrimPrefix(request.Path, salesforceApiPath)
+	}
+	return requests
+}
+
+func TestSalesforceSendLead(t *testing.T) {
+	t.Parallel()
+
+	server := newSalesforceTestServer(t)
+	client := NewSalesforceClient(server.URL, "token")
+
+	lead := map[string]any{
+		"company": map[string]any{
+			"entity": map[string]any{
+				"Name": "empresa d'existente",
+			},
+		},
+		"deal": map[string]any{
+			"entity": map[string]any{
+				"Name": "deal teste",
+			},
+		},
+		"contacts": []any{
+			map[string]any{
+				"entity": map[string]any{
+					"LastName": "contato teste 1",
+					"Email":    "fulano@teste.com",
+				},
+			},
+			map[string]any{
+				"entity": map[string]any{
+					"LastName": "contato teste 2",
+					"Email":    "fulano2@teste.com",
+				},
+			},
+		},
+	}
+	configs := map[string]any{
+		"owner_id":       "005OWNER",
+		"pipeline_id":    salesforcePipelineId,
+		"stage_id":       "Prospecting",
+		"create_deal":    true,
+		"overwrite_data": true,
+	}
+
+	createdLead, err := NewSalesforceService(nil).SendLead(client, lead, map[string]any{}, configs, map[string]any{})
+	require.NoError(t, err)
+
+	require.Equal(t, Updated, createdLead.Company.Status)
+	require.Equal(t, "001EXISTENTE", createdLead.Company.CrmId)
+	require.Equal(t, Created, createdLead.Deal.Status)
+	require.Len(t, *createdLead.Contacts, 2)
+
+	var createdDeal map[string]any
+	var contactRoles []map[string]any
+	var linkedContacts []string
+	dealLinked := false
+	for _, request := range getSalesforceRequests(t, server) {
+		switch {
+		case request.Method == "POST" && request.Path == "sobjects/Opportunity":
+			createdDeal = request.Body
+		case request.Method == "POST" && request.Path == "sobjects/OpportunityContactRole":
+			contactRoles = append(contactRoles, request.Body)
+		case request.Method == "PATCH" && request.Path == "sobjects/Opportunity/"+createdLead.Deal.CrmId.(string):
+			require.Equal(t, "001EXISTENTE", request.Body["AccountId"])
+			dealLinked = true
+		case request.Method == "PATCH" && strings.HasPrefix(request.Path, "sobjects/Contact/"):
+			require.Equal(t, "001EXISTENTE", request.Body["AccountId"])
+			linkedContacts = append(linkedContacts, strings.TrimPrefix(request.Path, "sobjects/Contact/"))
+		}
+	}
+
+	require.Equal(t, "Prospecting", createdDeal["StageName"])
+	require.Equal(t, "005OWNER", createdDeal["OwnerId"])
+	require.NotEmpty(t, createdDeal["CloseDate"])
+	require.True(t, dealLinked)
+	require.Len(t, contactRoles, 2)
+	require.Equal(t, true, contactRoles[0]["IsPrimary"])
+	require.Equal(t, false, contactRoles[1]["IsPrimary"])
+	require.ElementsMatch(t, []string{(*createdLead.Contacts)[0].CrmId.(string), (*createdLead.Contacts)[1].CrmId.(string)}, linkedContacts)
+}
+
+func TestSalesforceSendLeadExistingContactRoles(t *testing.T) {
+	t.Parallel()
+
+	server := newSalesforceTestServer(t)
+	client := NewSalesforceClient(server.URL, "token")
+
+	lead := map[string]any{
+		"deal": map[string]any{
+			"entity": map[string]any{
+				"Name": "deal teste",
+			},
+		},
+		"contacts": []any{
+			map[string]any{
+				"entity": map[string]any{
+					"LastName": "contato existente",
+					"Email":    "existente@teste.com",
+				},
+			},
+			map[string]any{
+				"entity": map[string]any{
+					"LastName": "contato novo",
+					"Email":    "novo@teste.com",
+				},
+			},
+		},
+	}
+	existingLead := map[string]any{
+		"deal": map[string]any{"crm_id": "006EXISTENTE", "status": "created"},
+	}
+	configs := map[string]any{
+		"owner_id":       "nenhum",
+		"pipeline_id":    salesforcePipelineId,
+		"stage_id":       "Prospecting",
+		"create_deal":    true,
+		"overwrite_data": false,
+	}
+
+	createdLead, err := NewSalesforceService(nil).SendLead(client, lead, map[string]any{}, configs, existingLead)
+	require.NoError(t, err)
+	require.Equal(t, Skipped, (*createdLead.Contacts)[0].Status)
+	require.Equal(t, Created, (*createdLead.Contacts)[1].Status)
+
+	var contactRoles []map[string]any
+	for _, request := range getSalesforceRequests(t, server) {
+		if request.Method == "POST" && request.Path == "sobjects/OpportunityContactRole" {
+			contactRoles = append(contactRoles, request.Body)
+		}
+	}
+
+	// só o contato novo ganha papel, e não como principal porque a oportunidade já tem um
+	require.Equal(t, []map[string]any{
+		{"OpportunityId": "006EXISTENTE", "ContactId": (*createdLead.Contacts)[1].CrmId, "IsPrimary": false},
+	}, contactRoles)
+}
+
+func TestSalesforceGetPipelines(t *testing.T) {
+	t.Parallel()
+
+	server := newSalesforceTestServer(t)
+	client := NewSalesforceClient(server.URL, "token")
+
+	pipelines, err := NewSalesforceService(nil).GetPipelines(client)
+	require.NoError(t, err)
+	require.Len(t, getSalesforceRequests(t, server), 1)
+	require.Equal(t, []Pipeline{
+		{
+			Id:   salesforcePipelineId,
+			Name: "Opportunity",
+			Stages: []Stage{
+				{Id: "Prospecting", Name: "Prospecção"},
+				{Id: "Closed Won", Name: "Ganho"},
+			},
+		},
+	}, pipelines)
+}
@@ -0,0 +1,416 @@
+package crm_exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"export-service/internal/core/ports"
+	"export-service/internal/repositories"
+	"export-service/internal/repositories/crm_company_repo"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	webhookSignatureHeader = "X-Driva-Signature"
+	webhookTimestampHeader = "X-Driva-Timestamp"
+	webhookDefaultTimeout  = 10 * time.Second
+	webhookMaxTimeout      = 120 * time.Second
+)
+
+// WebhookClient posts the leads to the url configured by the customer, signed with the secret of the installation
+type WebhookClient struct {
+	URL        string
+	Secret     string
+	HTTPClient *http.Client
+}
+
+func NewWebhookClient(webhookURL, secret string) *WebhookClient {
+	return &WebhookClient{
+		URL:        webhookURL,
+		Secret:     secret,
+		HTTPClient: newPublicHTTPClient(),
+	}
+}
+
+// newPublicHTTPClient only dials public addresses, for the urls given by the customer. the ip is checked at dial time
+// because the host could resolve to another address after the install
+func newPublicHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookDefaultTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isBlockedIP(ip) {
+				return fmt.Errorf("address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	// os redirects também passam pelo dialer
+	return &http.Client{Transport: transport}
+}
+
+// isBlockedIP refuses the addresses of our own network, like the cloud metadata at 169.254.169.254
+func isBlockedIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// validateWebhookURL only accepts https urls whose host resolves to public addresses
+func validateWebhookURL(ctx context.Context, webhookURL string) error {
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil || parsedURL.Scheme != "https" || parsedURL.Hostname() == "" {
+		return errors.New("a valid https url is required to install webhook")
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, parsedURL.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host: %w", err)
+	}
+	for _, address := range addresses {
+		if isBlockedIP(address.IP) {
+			return fmt.Errorf("webhook address %s is not allowed", address.IP)
+		}
+	}
+	return nil
+}
+
+// Sign returns the hex hmac sha256 of "<timestamp>.<body>", the timestamp is signed so old requests can't be replayed
+func (wc *WebhookClient) Sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(wc.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (wc *WebhookClient) Send(payload any, timeout time.Duration) (map[string]any, error) {
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", wc.URL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+wc.Sign(timestamp, jsonBody))
+
+	resp, err := wc.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook http error %d: %s", resp.StatusCode, responseBody)
+	}
+
+	if len(responseBody) == 0 {
+		return map[string]any{}, nil
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	return result, nil
+}
+
+type WebhookService struct {
+	companyRepo *crm_company_repo.PgCrmCompanyRepository
+}
+
+func NewWebhookService(companyRepo *crm_company_repo.PgCrmCompanyRepository) *WebhookService {
+	return &WebhookService{companyRepo: companyRepo}
+}
+
+// Authorize reads the url from the webhook column and the signing secret from the token column
+func (w *WebhookService) Authorize(ctx context.Context, workspaceId string) (any, error) {
+	company, err := w.companyRepo.GetCompanyByWorkspaceId(ctx, ports.CrmCompanyQueryParams{Crm: "webhook", WorkspaceId: workspaceId})
+	if err != nil {
+		return nil, err
+	}
+
+	if company.Webhook.String == "" {
+		return nil, errors.New("webhook url not found for " + workspaceId)
+	}
+
+	return NewWebhookClient(company.Webhook.String, company.Token.String), nil
+}
+
+func (w *WebhookService) Validate(c *fiber.Ctx, client any) bool {
+	webhookClient, ok := client.(*WebhookClient)
+	return ok && webhookClient.URL != ""
+}
+
+// Install saves the url of the install data, the secret is generated when not sent and returned so the customer can check the signatures
+func (w *WebhookService) Install(installData any) (any, error) {
+	installDataMap, isMap := installData.(map[string]any)
+	if !isMap {
+		return nil, errors.New("expected install data to be a map")
+	}
+
+	webhookURL, _ := installDataMap["url"].(string)
+	if err := validateWebhookURL(context.Background(), webhookURL); err != nil {
+		return nil, err
+	}
+
+	workspaceId, _ := installDataMap["workspace_id"].(string)
+	userId, _ := installDataMap["user_id"].(string)
+
+	_, err := w.companyRepo.GetCompanyByWorkspaceId(context.Background(), ports.CrmCompanyQueryParams{Crm: "webhook", WorkspaceId: workspaceId})
+	var companyNotFoundError repositories.CompanyNotFoundError
+	if !errors.As(err, &companyNotFoundError) {
+		return nil, errors.New("workspace already has an installation for webhook")
+	}
+
+	secret, _ := installDataMap["secret"].(string)
+	if secret == "" {
+		secret, err = generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = w.companyRepo.AddCompany(context.Background(), ports.CrmAddCompanyQueryParams{
+		Crm:         "webhook",
+		WorkspaceId: workspaceId,
+		UserId:      userId,
+		Token:       secret,
+		Webhook:     webhookURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{"installed": true, "secret": secret}, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func (w *WebhookService) OAuthCallback(c *fiber.Ctx, params ...any) (any, error) {
+	return nil, errors.New("webhook is installed with an url, oauth is not supported")
+}
+
+// getWebhookTimeout reads timeout_seconds from the export configs, it comes as any number type from the queue headers
+func getWebhookTimeout(configs map[string]any) time.Duration {
+	var seconds float64
+	switch v := configs["timeout_seconds"].(type) {
+	case float64:
+		seconds = v
+	case int:
+		seconds = float64(v)
+	case int32:
+		seconds = float64(v)
+	case int64:
+		seconds = float64(v)
+	case json.Number:
+		seconds, _ = v.Float64()
+	case string:
+		seconds, _ = strconv.ParseFloat(v, 64)
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout <= 0 {
+		return webhookDefaultTimeout
+	}
+	return min(timeout, webhookMaxTimeout)
+}
+
+// SendLead posts the company, deal and contacts that were not exported yet in a single request. the response
+// must have the id given by the customer system for each object, like {"company": {"id": 1}, "contacts": [{"id": 2}]},
+// contacts in the same order they were sent. an optional status (created, updated or skipped) and message are kept
+func (w *WebhookService) SendLead(client any, mappedStorageData map[string]any, correspondingRawData map[string]any, configs map[string]any, existingLead map[string]any) (CreatedLead, error) {
+	webhookClient, ok := client.(*WebhookClient)
+	if !ok {
+		return CreatedLead{}, errors.New("invalid Webhook client")
+	}
+
+	// sem a config, a negociação também é enviada
+	createDeal, isSet := configs["create_deal"].(bool)
+	if !isSet {
+		createDeal = true
+	}
+
+	createdLead := CreatedLead{}
+	payload := map[string]any{}
+	companyContactId, _ := correspondingRawData["company_contact_id"].(string)
+
+	if company, exists := mappedStorageData["company"]; exists {
+		if exportedCompany, exists := existingLead["company"].(map[string]any); exists && exportedCompany["crm_id"] != nil {
+			createdLead.Company = createExistingStatus(exportedCompany)
+		} else {
+			payload["company"] = withDrivaContactId(company, companyContactId)
+		}
+	}
+
+	if deal, exists := mappedStorageData["deal"]; exists && createDeal {
+		if exportedDeal, exists := existingLead["deal"].(map[string]any); exists && exportedDeal["crm_id"] != nil {
+			createdLead.Deal = createExistingStatus(exportedDeal)
+		} else {
+			payload["deal"] = withDrivaContactId(deal, companyContactId)
+		}
+	}
+
+	if contact, exists := mappedStorageData["contact"]; exists {
+		if exportedContact, exists := existingLead["contact"].(map[string]any); exists && exportedContact["crm_id"] != nil {
+			createdLead.Contacts = &[]ObjectStatus{*createExistingStatus(exportedContact)}
+		} else {
+			profileContactId, _ := correspondingRawData["profile_contact_id"].(string)
+			payload["contact"] = withDrivaContactId(contact, profileContactId)
+		}
+	}
+
+	// posição de cada contato pendente no resultado, para ler os ids na mesma ordem do envio
+	var pendingContacts []int
+	var contactsStatus []ObjectStatus
+	if contacts, exists := mappedStorageData["contacts"].([]any); exists {
+		var sentContacts []any
+		for key, contact := range contacts {
+			var profileContactId string
+			if profilesRaw, ok := correspondingRawData["profiles"].([]any); ok && key < len(profilesRaw) {
+				contactRawData, _ := profilesRaw[key].(map[string]any)
+				profileContactId, _ = contactRawData["profile_contact_id"].(string)
+			}
+
+			if exportedContact := findExportedContact(existingLead, profileContactId); exportedContact != nil {
+				contactsStatus = append(contactsStatus, *createExistingStatus(exportedContact))
+				continue
+			}
+
+			pendingContacts = append(pendingContacts, len(contactsStatus))
+			contactsStatus = append(contactsStatus, ObjectStatus{DrivaContactId: profileContactId})
+			sentContacts = append(sentContacts, withDrivaContactId(contact, profileContactId))
+		}
+
+		if len(sentContacts) > 0 {
+			payload["contacts"] = sentContacts
+		}
+		createdLead.Contacts = &contactsStatus
+	}
+
+	if len(payload) == 0 {
+		return createdLead, nil
+	}
+
+	response, err := webhookClient.Send(payload, getWebhookTimeout(configs))
+	if err != nil {
+		return createdLead, err
+	}
+
+	if _, sent := payload["company"]; sent {
+		createdLead.Company = getWebhookObjectStatus(response["company"], companyContactId)
+	}
+	if _, sent := payload["deal"]; sent {
+		createdLead.Deal = getWebhookObjectStatus(response["deal"], companyContactId)
+	}
+	if contact, sent := payload["contact"].(map[string]any); sent {
+		profileContactId, _ := contact["driva_contact_id"].(string)
+		createdLead.Contacts = &[]ObjectStatus{*getWebhookObjectStatus(response["contact"], profileContactId)}
+	}
+
+	returnedContacts, _ := response["contacts"].([]any)
+	for i, statusIndex := range pendingContacts {
+		var returnedContact any
+		if i < len(returnedContacts) {
+			returnedContact = returnedContacts[i]
+		}
+		contactsStatus[statusIndex] = *getWebhookObjectStatus(returnedContact, contactsStatus[statusIndex].DrivaContactId)
+	}
+
+	return createdLead, nil
+}
+
+// withDrivaContactId sends our id with the object, so the customer system can also deduplicate
+func withDrivaContactId(object any, drivaContactId string) any {
+	objectMap, ok := object.(map[string]any)
+	if !ok || drivaContactId == "" {
+		return object
+	}
+
+	withId := make(map[string]any, len(objectMap)+1)
+	for key, value := range objectMap {
+		withId[key] = value
+	}
+	withId["driva_contact_id"] = drivaContactId
+	return withId
+}
+
+func getWebhookObjectStatus(returned any, drivaContactId string) *ObjectStatus {
+	returnedMap, _ := returned.(map[string]any)
+	id, exists := returnedMap["id"]
+	if !exists || id == nil || id == "" {
+		return &ObjectStatus{
+			Status:         Failed,
+			Message:        "id not returned by webhook",
+			DrivaContactId: drivaContactId,
+		}
+	}
+
+	status := Created
+	switch Status(safeString(returnedMap, "status")) {
+	case Updated:
+		status = Updated
+	case Skipped:
+		status = Skipped
+	}
+
+	return &ObjectStatus{
+		CrmId:          id,
+		Status:         status,
+		Message:        safeString(returnedMap, "message"),
+		DrivaContactId: drivaContactId,
+	}
+}
+
+// the customer system has no pipelines, fields or owners to list
+func (w *WebhookService) GetPipelines(client any) ([]Pipeline, error) {
+	return []Pipeline{}, nil
+}
+
+func (w *WebhookService) GetFields(client any) (CrmFields, error) {
+	return CrmFields{}, nil
+}
+
+func (w *WebhookService) GetOwners(client any) ([]Owner, error) {
+	return []Owner{}, nil
+}
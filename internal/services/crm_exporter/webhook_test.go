@@ -0,0 +1,125 @@
+package crm_exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSendLead(t *testing.T) {
+	t.Parallel()
+
+	server := newCrmTestServer(t, func(request crmRequest) (int, any) {
+		return http.StatusOK, map[string]any{
+			"deal":     map[string]any{"id": "deal-1", "status": "updated"},
+			"contacts": []any{map[string]any{"id": 33}, map[string]any{}},
+		}
+	})
+
+	lead := map[string]any{
+		"company": map[string]any{"entity": map[string]any{"name": "empresa teste"}},
+		"deal":    map[string]any{"entity": map[string]any{"name": "deal teste"}},
+		"contacts": []any{
+			map[string]any{"entity": map[string]any{"name": "contato 1"}},
+			map[string]any{"entity": map[string]any{"name": "contato 2"}},
+			map[string]any{"entity": map[string]any{"name": "contato 3"}},
+		},
+	}
+	rawLead := map[string]any{
+		"company_contact_id": "c1",
+		"profiles": []any{
+			map[string]any{"profile_contact_id": "p1"},
+			map[string]any{"profile_contact_id": "p2"},
+			map[string]any{"profile_contact_id": "p3"},
+		},
+	}
+	// a empresa e o segundo contato já foram exportados numa execução anterior
+	existingLead := map[string]any{
+		"company": map[string]any{"crm_id": "company-1", "status": "created", "driva_contact_id": "c1"},
+		"contacts": []any{
+			map[string]any{"crm_id": 22, "status": "created", "driva_contact_id": "p2"},
+		},
+	}
+
+	client := NewWebhookClient(server.URL, "segredo")
+	// o servidor de teste escuta no loopback, que o client padrão recusa
+	client.HTTPClient = server.Client()
+	createdLead, err := NewWebhookService(nil).SendLead(client, lead, rawLead, map[string]any{"create_deal": true}, existingLead)
+	require.NoError(t, err)
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	signature := NewWebhookClient("", "segredo").Sign(requests[0].Header.Get(webhookTimestampHeader), requests[0].RawBody)
+	require.Equal(t, "sha256="+signature, requests[0].Header.Get(webhookSignatureHeader))
+
+	received := requests[0].Body
+	require.NotContains(t, received, "company")
+	require.Equal(t, map[string]any{"entity": map[string]any{"name": "deal teste"}, "driva_contact_id": "c1"}, received["deal"])
+	require.Equal(t, []any{
+		map[string]any{"entity": map[string]any{"name": "contato 1"}, "driva_contact_id": "p1"},
+		map[string]any{"entity": map[string]any{"name": "contato 3"}, "driva_contact_id": "p3"},
+	}, received["contacts"])
+
+	require.Equal(t, "company-1", createdLead.Company.CrmId)
+	require.Equal(t, &ObjectStatus{CrmId: "deal-1", Status: Updated, DrivaContactId: "c1"}, createdLead.Deal)
+	require.Equal(t, []ObjectStatus{
+		{CrmId: float64(33), Status: Created, DrivaContactId: "p1"},
+		{CrmId: 22, Status: Created, DrivaContactId: "p2"},
+		{Status: Failed, Message: "id not returned by webhook", DrivaContactId: "p3"},
+	}, *createdLead.Contacts)
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	t.Cleanup(server.Close)
+
+	lead := map[string]any{"company": map[string]any{"entity": map[string]any{"name": "empresa teste"}}}
+	client := NewWebhookClient(server.URL, "segredo")
+	client.HTTPClient = server.Client()
+
+	_, err := NewWebhookService(nil).SendLead(client, lead, map[string]any{}, map[string]any{"timeout_seconds": 0.1}, map[string]any{})
+	require.ErrorContains(t, err, "deadline exceeded")
+
+	require.Equal(t, webhookDefaultTimeout, getWebhookTimeout(map[string]any{}))
+	require.Equal(t, webhookMaxTimeout, getWebhookTimeout(map[string]any{"timeout_seconds": int64(3600)}))
+}
+
+func TestWebhookBlockedAddresses(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Should refuse to install urls that are not https or point to internal addresses", func(t *testing.T) {
+		urls := []string{
+			"",
+			"http://example.com/hook",
+			"https://127.0.0.1/hook",
+			"https://localhost/hook",
+			"https://[::1]/hook",
+			"https://10.0.0.1/hook",
+			"https://192.168.0.10/hook",
+			"https://169.254.169.254/latest/meta-data",
+			"https://0.0.0.0/hook",
+		}
+		for _, webhookURL := range urls {
+			_, err := NewWebhookService(nil).Install(map[string]any{"url": webhookURL, "workspace_id": "1"})
+			require.Error(t, err, webhookURL)
+		}
+	})
+
+	t.Run("Should refuse to send to internal addresses", func(t *testing.T) {
+		server := newCrmTestServer(t, func(request crmRequest) (int, any) {
+			return http.StatusOK, map[string]any{}
+		})
+
+		lead := map[string]any{"company": map[string]any{"entity": map[string]any{"name": "empresa teste"}}}
+		_, err := NewWebhookService(nil).SendLead(NewWebhookClient(server.URL, "segredo"), lead, map[string]any{}, map[string]any{}, map[string]any{})
+		require.ErrorContains(t, err, "is not allowed")
+		require.Empty(t, server.Requests())
+	})
+}
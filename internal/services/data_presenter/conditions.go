@@ -0,0 +1,271 @@
+package data_presenter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fieldGetter returns the value of a field of the data being tested and whether it exists
+type fieldGetter func(field string) (any, bool, error)
+
+// matchConditions is the condition language shared by $switch, $for.$filter and any other conditional keyword.
+// every key is a field tested against its condition, or one of $and, $or and $not taking nested condition maps.
+// the keys of the map are combined with OR when anyKey is set and with AND otherwise, nested maps always use AND.
+// an empty map matches nothing with OR and everything with AND
+func matchConditions(conditions map[string]any, getField fieldGetter, anyKey bool) (bool, error) {
+	for key, condition := range conditions {
+		match, err := matchKey(key, condition, getField)
+		if err != nil {
+			return false, err
+		}
+
+		if match && anyKey {
+			return true, nil
+		}
+		if !match && !anyKey {
+			return false, nil
+		}
+	}
+	return !anyKey, nil
+}
+
+func matchKey(key string, condition any, getField fieldGetter) (bool, error) {
+	switch key {
+	case "$and", "$or":
+		list, isList := condition.([]any)
+		if !isList {
+			return false, errors.New(key + " requires an array of condition maps")
+		}
+
+		for _, item := range list {
+			itemConditions, isMap := item.(map[string]any)
+			if !isMap {
+				return false, errors.New(key + " requires an array of condition maps")
+			}
+
+			match, err := matchConditions(itemConditions, getField, false)
+			if err != nil {
+				return false, err
+			}
+
+			if match && key == "$or" {
+				return true, nil
+			}
+			if !match && key == "$and" {
+				return false, nil
+			}
+		}
+		return key == "$and", nil
+	case "$not":
+		notConditions, isMap := condition.(map[string]any)
+		if !isMap {
+			return false, errors.New("$not requires a condition map")
+		}
+
+		match, err := matchConditions(notConditions, getField, false)
+		return !match, err
+	}
+
+	value, exists, err := getField(key)
+	if err != nil {
+		return false, err
+	}
+	return matchValue(value, exists, condition)
+}
+
+// matchValue tests a value against a map of operators, which must all be true, or compares it to the condition.
+// a missing field is not equal to anything, not even to null
+func matchValue(value any, exists bool, condition any) (bool, error) {
+	operators, isMap := condition.(map[string]any)
+	if !isMap || !isOperatorMap(operators) {
+		return exists && valuesEqual(value, condition), nil
+	}
+
+	for operator, operand := range operators {
+		match, err := matchOperator(operator, operand, value, exists)
+		if err != nil {
+			return false, err
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(operator string, operand, value any, exists bool) (bool, error) {
+	switch operator {
+	case "$eq":
+		return exists && valuesEqual(value, operand), nil
+	case "$ne":
+		return !exists || !valuesEqual(value, operand), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		comparison, comparable := compareValues(value, operand)
+		if !comparable {
+			return false, nil
+		}
+		switch operator {
+		case "$gt":
+			return comparison > 0, nil
+		case "$gte":
+			return comparison >= 0, nil
+		case "$lt":
+			return comparison < 0, nil
+		default:
+			return comparison <= 0, nil
+		}
+	case "$in", "$nin":
+		list, isList := operand.([]any)
+		if !isList {
+			return false, errors.New(operator + " requires an array")
+		}
+
+		in := false
+		for _, v := range asList(value) {
+			for _, item := range list {
+				if valuesEqual(v, item) {
+					in = true
+				}
+			}
+		}
+		return in == (operator == "$in"), nil
+	case "$exists":
+		shouldExist, isBool := operand.(bool)
+		if !isBool {
+			return false, errors.New("$exists requires a boolean")
+		}
+		return (exists && value != nil) == shouldExist, nil
+	case "$regex":
+		pattern, isString := operand.(string)
+		if !isString {
+			return false, errors.New("$regex requires a string")
+		}
+
+		re, err := compileRegex(pattern)
+		if err != nil {
+			return false, err
+		}
+
+		for _, v := range asList(value) {
+			if s, isString := v.(string); isString && re.MatchString(s) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$contains":
+		if s, isString := value.(string); isString {
+			return strings.Contains(s, fmt.Sprintf("%v", operand)), nil
+		}
+
+		list, isList := value.([]any)
+		if !isList {
+			return false, nil
+		}
+		for _, item := range list {
+			if valuesEqual(item, operand) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$not":
+		match, err := matchValue(value, exists, operand)
+		return !match, err
+	}
+
+	return false, errors.New("unknown condition operator " + operator)
+}
+
+func isOperatorMap(condition map[string]any) bool {
+	if len(condition) == 0 {
+		return false
+	}
+	for key := range condition {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares numbers by value, since data decoded from json has float64 numbers and specs from yaml have int
+func valuesEqual(a, b any) bool {
+	aNumber, aIsNumber := conditionNumber(a)
+	bNumber, bIsNumber := conditionNumber(b)
+	if aIsNumber && bIsNumber {
+		return aNumber == bNumber
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues compares numbers, numeric strings included, or else strings, which works for ISO dates
+func compareValues(a, b any) (int, bool) {
+	aNumber, aOk := conditionNumberOrNumericString(a)
+	bNumber, bOk := conditionNumberOrNumericString(b)
+	if aOk && bOk {
+		switch {
+		case aNumber < bNumber:
+			return -1, true
+		case aNumber > bNumber:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	aString, aIsString := a.(string)
+	bString, bIsString := b.(string)
+	if aIsString && bIsString {
+		return strings.Compare(aString, bString), true
+	}
+	return 0, false
+}
+
+func conditionNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func conditionNumberOrNumericString(value any) (float64, bool) {
+	if s, isString := value.(string); isString {
+		number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return number, err == nil
+	}
+	return conditionNumber(value)
+}
+
+func asList(value any) []any {
+	if list, isList := value.([]any); isList {
+		return list
+	}
+	return []any{value}
+}
+
+// the same $regex is tested against every record of an export, so each pattern is compiled once
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, exists := regexCache.Load(pattern); exists {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $regex %q: %w", pattern, err)
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
@@ -2,12 +2,47 @@ package data_presenter
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
+const (
+	dateEpoch       = "epoch"
+	dateEpochMillis = "epoch_millis"
+	dateIso         = "iso"
+	dateYearsSince  = "years_since"
+	dateMonthsSince = "months_since"
+	dateDaysSince   = "days_since"
+)
+
+var defaultDateInputs = []string{"2006-01-02", "2006-01-02T15:04:05Z"}
+
+const defaultDateOutput = "02-01-2006"
+
+// usado pelas saídas relativas, substituído nos testes
+var timeNow = time.Now
+
+// $date takes a prop or a map with $prop, $input (a layout or list of layouts tried in order, epoch or epoch_millis),
+// $output (a layout, iso, epoch, epoch_millis, years_since, months_since or days_since) and $timezone
 func handleDate(source map[string]any, location any) (any, error) {
+	inputs := defaultDateInputs
+	output := defaultDateOutput
+	timezone := time.UTC
+
+	prop := location
+	if mapLocation, isMap := location.(map[string]any); isMap {
+		if _, exists := mapLocation["$prop"]; exists {
+			prop = mapLocation["$prop"]
+
+			var err error
+			if inputs, output, timezone, err = getDateOptions(mapLocation); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	handler := NewKeywordHandler()
-	result, err := handler.HandleKeywords(source, location)
+	result, err := handler.HandleKeywords(source, prop)
 	if err != nil {
 		return nil, err
 	}
@@ -16,25 +51,117 @@ func handleDate(source map[string]any, location any) (any, error) {
 		return nil, nil
 	}
 
-	var dateInput string
-	if dateStr, isString := result.(string); isString {
-		dateInput = dateStr
-	} else {
-		return nil, errors.New("$date location must be a date formatted YYYY-MM-DD")
+	date, err := parseDate(result, inputs, timezone)
+	if err != nil {
+		return nil, err
 	}
 
-	inputLayout := "2006-01-02"
-	date, err := time.Parse(inputLayout, dateInput)
-	if err != nil {
-		date, err = time.Parse("2006-01-02T15:04:05Z", dateInput)
+	return formatDate(date.In(timezone), output), nil
+}
+
+func getDateOptions(mapLocation map[string]any) ([]string, string, *time.Location, error) {
+	inputs := defaultDateInputs
+	switch v := mapLocation["$input"].(type) {
+	case nil:
+	case string:
+		inputs = []string{v}
+	case []any:
+		inputs = make([]string, 0, len(v))
+		for _, input := range v {
+			stringInput, isString := input.(string)
+			if !isString {
+				return nil, "", nil, errors.New("invalid data type for $input in $date. must be a string or array of strings")
+			}
+			inputs = append(inputs, stringInput)
+		}
+	default:
+		return nil, "", nil, errors.New("invalid data type for $input in $date. must be a string or array of strings")
+	}
+
+	output := defaultDateOutput
+	if customOutput, exists := mapLocation["$output"]; exists {
+		stringOutput, isString := customOutput.(string)
+		if !isString {
+			return nil, "", nil, errors.New("invalid data type for $output in $date. must be a string")
+		}
+		output = stringOutput
+	}
+
+	timezone := time.UTC
+	if customTimezone, exists := mapLocation["$timezone"]; exists {
+		stringTimezone, isString := customTimezone.(string)
+		if !isString {
+			return nil, "", nil, errors.New("invalid data type for $timezone in $date. must be a string")
+		}
+
+		location, err := time.LoadLocation(stringTimezone)
 		if err != nil {
-			return nil, errors.New("$date location must be a date formatted YYYY-MM-DD or YYYY-MM-DDTHH:MM:SSZ")
+			return nil, "", nil, fmt.Errorf("invalid $timezone in $date: %w", err)
+		}
+		timezone = location
+	}
+
+	return inputs, output, timezone, nil
+}
+
+// dates without a timezone in the input are read in the configured timezone
+func parseDate(value any, inputs []string, timezone *time.Location) (time.Time, error) {
+	for _, input := range inputs {
+		switch input {
+		case dateEpoch, dateEpochMillis:
+			number, err := toMathNumber(value, "$date")
+			if err != nil || number == nil {
+				continue
+			}
+			if input == dateEpoch {
+				return time.Unix(int64(*number), 0), nil
+			}
+			return time.UnixMilli(int64(*number)), nil
+		default:
+			stringValue, isString := value.(string)
+			if !isString {
+				continue
+			}
+			if date, err := time.ParseInLocation(input, stringValue, timezone); err == nil {
+				return date, nil
+			}
 		}
 	}
 
-	outputLayout := "02-01-2006"
+	return time.Time{}, fmt.Errorf("$date value %v does not match any of the input layouts %v", value, inputs)
+}
 
-	formattedDate := date.Format(outputLayout)
+func formatDate(date time.Time, output string) any {
+	switch output {
+	case dateIso:
+		return date.Format(time.RFC3339)
+	case dateEpoch:
+		return int(date.Unix())
+	case dateEpochMillis:
+		return int(date.UnixMilli())
+	case dateYearsSince:
+		years, _ := getTimeSince(date)
+		return years
+	case dateMonthsSince:
+		years, months := getTimeSince(date)
+		return years*12 + months
+	case dateDaysSince:
+		return int(timeNow().Sub(date).Hours() / 24)
+	}
+	return date.Format(output)
+}
 
-	return formattedDate, nil
+// getTimeSince returns the complete years and remaining months between the date and now
+func getTimeSince(date time.Time) (int, int) {
+	now := timeNow().In(date.Location())
+	years := now.Year() - date.Year()
+	months := int(now.Month()) - int(date.Month())
+	if now.Day() < date.Day() {
+		months--
+	}
+	if months < 0 {
+		years--
+		months += 12
+	}
+	return years, months
 }
@@ -0,0 +1,34 @@
+package data_presenter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDate_RelativeOutputs(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2024, 11, 17, 12, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	source := map[string]any{"data_inicio_atividade": "2011-11-18"}
+	tests := map[string]any{
+		dateYearsSince:  12,
+		dateMonthsSince: 155,
+		dateDaysSince:   4748,
+	}
+
+	for output, expected := range tests {
+		t.Run(output, func(t *testing.T) {
+			result, err := handleDate(source, map[string]any{"$prop": "data_inicio_atividade", "$output": output})
+			require.NoError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+
+	t.Run("Should fail when no input layout matches", func(t *testing.T) {
+		_, err := handleDate(map[string]any{"data": "18/11/2011"}, map[string]any{"$prop": "data"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package data_presenter
+
+import (
+	"encoding/json"
+	"export-service/internal/core/domain"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// DiffSpecVersions lists what changed from one version of a spec to another. sheet options are compared by key,
+// so reordering them only shows the positions that changed
+func DiffSpecVersions(from, to domain.PresentationSpecVersion) []domain.PresentationSpecChange {
+	changes := []domain.PresentationSpecChange{}
+	diffValues(&changes, "spec", toGeneric(from.Spec), toGeneric(to.Spec))
+	diffValues(&changes, "sheet_options", getOptionsByKey(from.SheetOptions), getOptionsByKey(to.SheetOptions))
+	return changes
+}
+
+func getOptionsByKey(options []domain.PresentationSpecSheetOptions) any {
+	byKey := make(map[string]domain.PresentationSpecSheetOptions, len(options))
+	for _, option := range options {
+		byKey[option.Key] = option
+	}
+	return toGeneric(byKey)
+}
+
+// toGeneric converts a value to the maps and slices it has in json, so both sides are compared the same way
+func toGeneric(value any) any {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return value
+	}
+	return generic
+}
+
+func diffValues(changes *[]domain.PresentationSpecChange, path string, from, to any) {
+	fromMap, fromIsMap := from.(map[string]any)
+	toMap, toIsMap := to.(map[string]any)
+	if fromIsMap && toIsMap {
+		keys := make(map[string]bool, len(fromMap)+len(toMap))
+		for key := range fromMap {
+			keys[key] = true
+		}
+		for key := range toMap {
+			keys[key] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			fromValue, inFrom := fromMap[key]
+			toValue, inTo := toMap[key]
+			switch {
+			case !inFrom:
+				*changes = append(*changes, domain.PresentationSpecChange{Path: joinPath(path, key), Type: changeAdded, To: toValue})
+			case !inTo:
+				*changes = append(*changes, domain.PresentationSpecChange{Path: joinPath(path, key), Type: changeRemoved, From: fromValue})
+			default:
+				diffValues(changes, joinPath(path, key), fromValue, toValue)
+			}
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]any)
+	toList, toIsList := to.([]any)
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(fromList):
+				*changes = append(*changes, domain.PresentationSpecChange{Path: itemPath, Type: changeAdded, To: toList[i]})
+			case i >= len(toList):
+				*changes = append(*changes, domain.PresentationSpecChange{Path: itemPath, Type: changeRemoved, From: fromList[i]})
+			default:
+				diffValues(changes, itemPath, fromList[i], toList[i])
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, domain.PresentationSpecChange{Path: path, Type: changeChanged, From: from, To: to})
+	}
+}
@@ -0,0 +1,49 @@
+package data_presenter_test
+
+import (
+	"export-service/internal/core/domain"
+	"export-service/internal/services/data_presenter"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSpecVersions(t *testing.T) {
+	from := domain.PresentationSpecVersion{
+		Version: 2,
+		Spec: domain.PresentationSpecSpec{
+			"RFB": {"CNPJ": "cnpj", "Nome": "razao_social"},
+			"Telefones": {"Telefone": map[string]any{
+				"$fallback": []any{"telefone", "celular"},
+			}},
+		},
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ", "Nome"}, Position: 0},
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 1},
+		},
+	}
+	to := domain.PresentationSpecVersion{
+		Version: 3,
+		Spec: domain.PresentationSpecSpec{
+			"RFB": {"CNPJ": "cnpj", "Nome": "nome_fantasia", "Site": "site"},
+			"Telefones": {"Telefone": map[string]any{
+				"$fallback": []any{"telefone"},
+			}},
+		},
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 0},
+			{Key: "RFB", ActiveColumns: []string{"CNPJ", "Nome", "Site"}, Position: 1},
+		},
+	}
+
+	assert.Equal(t, []domain.PresentationSpecChange{
+		{Path: "spec.RFB.Nome", Type: "changed", From: "razao_social", To: "nome_fantasia"},
+		{Path: "spec.RFB.Site", Type: "added", To: "site"},
+		{Path: "spec.Telefones.Telefone.$fallback[1]", Type: "removed", From: "celular"},
+		{Path: "sheet_options.RFB.active_columns[2]", Type: "added", To: "Site"},
+		{Path: "sheet_options.RFB.position", Type: "changed", From: float64(0), To: float64(1)},
+		{Path: "sheet_options.Telefones.position", Type: "changed", From: float64(1), To: float64(0)},
+	}, data_presenter.DiffSpecVersions(from, to))
+
+	assert.Empty(t, data_presenter.DiffSpecVersions(from, from))
+}
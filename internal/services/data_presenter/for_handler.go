@@ -2,7 +2,7 @@ package data_presenter
 
 import (
 	"errors"
-	"reflect"
+	"strings"
 )
 
 func handleFor(source map[string]any, location any) (any, error) {
@@ -61,6 +61,16 @@ func handleFor(source map[string]any, location any) (any, error) {
 		}
 	}
 
+	// $drop_nulls descarta os itens cujo $format resultou em nulo, como os telefones inválidos do $phone
+	var dropNulls bool
+	if dropNullsKey, exists := mapLocation["$drop_nulls"]; exists {
+		boolDrop, isBool := dropNullsKey.(bool)
+		if !isBool {
+			return nil, errors.New("invalid data type for $drop_nulls keyword. must be a bool")
+		}
+		dropNulls = boolDrop
+	}
+
 	var result any
 	var err error
 	stringProp, isString := prop.(string)
@@ -92,12 +102,15 @@ func handleFor(source map[string]any, location any) (any, error) {
 		}
 
 		if filter != nil {
-			match := true
-			for key, condition := range filter {
-				if itemVal, exists := mapValue[key]; !exists || !reflect.DeepEqual(itemVal, condition) {
-					match = false
-					break
+			match, err := matchConditions(filter, func(field string) (any, bool, error) {
+				if itemVal, exists := mapValue[field]; exists || !strings.Contains(field, ".") {
+					return itemVal, exists, nil
 				}
+				itemVal, err := getNestedValue(mapValue, field)
+				return itemVal, itemVal != nil, err
+			}, false)
+			if err != nil {
+				return nil, err
 			}
 
 			if !match {
@@ -123,6 +136,10 @@ func handleFor(source map[string]any, location any) (any, error) {
 			return forResult, nil
 		}
 
+		if dropNulls && res == nil {
+			continue
+		}
+
 		if !indexExists {
 			forResult = append(forResult, res)
 		}
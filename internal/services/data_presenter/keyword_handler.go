@@ -33,10 +33,24 @@ func NewKeywordHandler() *KeywordHandler {
 			"$switch":          handleSwitch,
 			"$firstname":       handleFirstName,
 			"$lastname":        handleLastName,
+			"$sum":             handleSum,
+			"$avg":             handleAvg,
+			"$min":             handleMin,
+			"$max":             handleMax,
+			"$count":           handleCount,
+			"$round":           handleRound,
+			"$multiply":        handleMultiply,
+			"$divide":          handleDivide,
+			"$percent":         handlePercent,
 		},
 	}
 }
 
+func (h *KeywordHandler) HasKeyword(keyword string) bool {
+	_, exists := h.handlers[keyword]
+	return exists
+}
+
 func (h *KeywordHandler) HandleKeywords(source map[string]any, spec any) (any, error) {
 	if stringSpec, isString := spec.(string); isString {
 		return getNestedValue(source, stringSpec)
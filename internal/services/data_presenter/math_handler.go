@@ -0,0 +1,257 @@
+package data_presenter
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// every math keyword accepts props, nested keywords and number literals as operands.
+// arrays, like the ones returned by $for, are flattened and nil values are ignored by the aggregations
+
+func handleSum(source map[string]any, location any) (any, error) {
+	numbers, err := getNumbers(source, location, "$sum")
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+
+	sum := 0.0
+	for _, number := range numbers {
+		sum += number
+	}
+	return sum, nil
+}
+
+func handleAvg(source map[string]any, location any) (any, error) {
+	numbers, err := getNumbers(source, location, "$avg")
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+
+	sum := 0.0
+	for _, number := range numbers {
+		sum += number
+	}
+	return sum / float64(len(numbers)), nil
+}
+
+func handleMin(source map[string]any, location any) (any, error) {
+	numbers, err := getNumbers(source, location, "$min")
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+
+	min := numbers[0]
+	for _, number := range numbers[1:] {
+		min = math.Min(min, number)
+	}
+	return min, nil
+}
+
+func handleMax(source map[string]any, location any) (any, error) {
+	numbers, err := getNumbers(source, location, "$max")
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+
+	max := numbers[0]
+	for _, number := range numbers[1:] {
+		max = math.Max(max, number)
+	}
+	return max, nil
+}
+
+// $count counts the values found, numbers or not
+func handleCount(source map[string]any, location any) (any, error) {
+	values, err := getMathValues(source, location)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for _, value := range values {
+		if value != nil && value != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// $round takes a single operand or a map with $prop and $decimals, which defaults to 0
+func handleRound(source map[string]any, location any) (any, error) {
+	prop := location
+	decimals := 0
+	if mapLocation, isMap := location.(map[string]any); isMap {
+		if _, exists := mapLocation["$prop"]; exists {
+			prop = mapLocation["$prop"]
+			var err error
+			if decimals, err = getDecimals(mapLocation, "$round"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	number, err := getSingleNumber(source, prop, "$round")
+	if err != nil || number == nil {
+		return nil, err
+	}
+	return roundTo(*number, decimals), nil
+}
+
+func handleMultiply(source map[string]any, location any) (any, error) {
+	operands, isArray := location.([]any)
+	if !isArray || len(operands) < 2 {
+		return nil, errors.New("$multiply requires an array with at least two operands")
+	}
+
+	product := 1.0
+	for _, operand := range operands {
+		number, err := getSingleNumber(source, operand, "$multiply")
+		if err != nil || number == nil {
+			return nil, err
+		}
+		product *= *number
+	}
+	return product, nil
+}
+
+// $divide returns nil when dividing by zero, the same as a missing value
+func handleDivide(source map[string]any, location any) (any, error) {
+	dividend, divisor, err := getFraction(source, location, "$divide")
+	if err != nil || dividend == nil || divisor == nil || *divisor == 0 {
+		return nil, err
+	}
+	return *dividend / *divisor, nil
+}
+
+// $percent takes [part, total] or a map with $value, $total and $decimals, which defaults to 2
+func handlePercent(source map[string]any, location any) (any, error) {
+	decimals := 2
+	fraction := location
+	if mapLocation, isMap := location.(map[string]any); isMap {
+		if _, exists := mapLocation["$decimals"]; exists {
+			var err error
+			if decimals, err = getDecimals(mapLocation, "$percent"); err != nil {
+				return nil, err
+			}
+		}
+		fraction = []any{mapLocation["$value"], mapLocation["$total"]}
+	}
+
+	part, total, err := getFraction(source, fraction, "$percent")
+	if err != nil || part == nil || total == nil || *total == 0 {
+		return nil, err
+	}
+	return roundTo(*part / *total * 100, decimals), nil
+}
+
+func getFraction(source map[string]any, location any, keyword string) (*float64, *float64, error) {
+	operands, isArray := location.([]any)
+	if !isArray || len(operands) != 2 {
+		return nil, nil, errors.New(keyword + " requires an array with two operands")
+	}
+
+	numerator, err := getSingleNumber(source, operands[0], keyword)
+	if err != nil {
+		return nil, nil, err
+	}
+	denominator, err := getSingleNumber(source, operands[1], keyword)
+	if err != nil {
+		return nil, nil, err
+	}
+	return numerator, denominator, nil
+}
+
+func getDecimals(mapLocation map[string]any, keyword string) (int, error) {
+	switch v := mapLocation["$decimals"].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	}
+	return 0, errors.New("invalid data type for $decimals in " + keyword + ". must be an int")
+}
+
+func roundTo(number float64, decimals int) float64 {
+	pow := math.Pow(10, float64(decimals))
+	return math.Round(number*pow) / pow
+}
+
+// getMathValues resolves an operand or an array of operands into a flat list of values
+func getMathValues(source map[string]any, location any) ([]any, error) {
+	operands, isArray := location.([]any)
+	if !isArray {
+		operands = []any{location}
+	}
+
+	handler := NewKeywordHandler()
+	var values []any
+	for _, operand := range operands {
+		var value any
+		if _, isNumber := conditionNumber(operand); isNumber {
+			value = operand
+		} else {
+			var err error
+			if value, err = handler.HandleKeywords(source, operand); err != nil {
+				return nil, err
+			}
+		}
+
+		if arrayValue, isArray := value.([]any); isArray {
+			values = append(values, flatMap(arrayValue)...)
+		} else {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func getNumbers(source map[string]any, location any, keyword string) ([]float64, error) {
+	values, err := getMathValues(source, location)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make([]float64, 0, len(values))
+	for _, value := range values {
+		number, err := toMathNumber(value, keyword)
+		if err != nil {
+			return nil, err
+		}
+		if number != nil {
+			numbers = append(numbers, *number)
+		}
+	}
+	return numbers, nil
+}
+
+func getSingleNumber(source map[string]any, location any, keyword string) (*float64, error) {
+	numbers, err := getNumbers(source, location, keyword)
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+	if len(numbers) > 1 {
+		return nil, errors.New(keyword + " requires single values, use $sum to aggregate arrays")
+	}
+	return &numbers[0], nil
+}
+
+func toMathNumber(value any, keyword string) (*float64, error) {
+	if value == nil || value == "" {
+		return nil, nil
+	}
+
+	if number, isNumber := conditionNumber(value); isNumber {
+		return &number, nil
+	}
+
+	if stringValue, isString := value.(string); isString {
+		if number, err := strconv.ParseFloat(strings.TrimSpace(stringValue), 64); err == nil {
+			return &number, nil
+		}
+	}
+	return nil, errors.New(keyword + " requires numbers or strings that can be converted to numbers")
+}
@@ -2,22 +2,175 @@ package data_presenter
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
+const (
+	phoneInternational = "international"
+	phoneE164          = "e164"
+	phoneNational      = "national"
+	phoneFormatted     = "formatted"
+	phoneType          = "type"
+)
+
+const brazilCountryCode = "55"
+
+var validDDDs = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true, "22": true, "24": true, "27": true, "28": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "37": true, "38": true,
+	"41": true, "42": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "53": true, "54": true, "55": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true, "66": true, "67": true, "68": true, "69": true,
+	"71": true, "73": true, "74": true, "75": true, "77": true, "79": true,
+	"81": true, "82": true, "83": true, "84": true, "85": true, "86": true, "87": true, "88": true, "89": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true, "98": true, "99": true,
+}
+
+type phoneNumber struct {
+	countryCode string
+	ddd         string
+	number      string
+	mobile      bool
+}
+
+// $phone takes a prop or a map with $prop, $format (international, e164, national, formatted or type)
+// and $drop_invalid. invalid numbers are kept as they are unless $drop_invalid is set
 func handlePhone(source map[string]any, location any) (any, error) {
+	prop := location
+	format := phoneInternational
+	dropInvalid := false
+	if mapLocation, isMap := location.(map[string]any); isMap {
+		if _, exists := mapLocation["$prop"]; exists {
+			prop = mapLocation["$prop"]
+
+			if customFormat, exists := mapLocation["$format"]; exists {
+				stringFormat, isString := customFormat.(string)
+				if !isString {
+					return nil, errors.New("invalid data type for $format in $phone. must be a string")
+				}
+				format = stringFormat
+			}
+
+			if customDrop, exists := mapLocation["$drop_invalid"]; exists {
+				boolDrop, isBool := customDrop.(bool)
+				if !isBool {
+					return nil, errors.New("invalid data type for $drop_invalid in $phone. must be a bool")
+				}
+				dropInvalid = boolDrop
+			}
+		}
+	}
+
+	switch format {
+	case phoneInternational, phoneE164, phoneNational, phoneFormatted, phoneType:
+	default:
+		return nil, errors.New("unknown $format " + format + " in $phone")
+	}
+
 	handler := NewKeywordHandler()
-	result, err := handler.HandleKeywords(source, location)
+	result, err := handler.HandleKeywords(source, prop)
 	if err != nil {
 		return nil, err
 	}
-	if result == nil {
+	if result == nil || result == "" {
 		return nil, nil
 	}
 
-	stringResult, isString := result.(string)
-	if !isString {
+	var stringResult string
+	switch v := result.(type) {
+	case string:
+		stringResult = v
+	case float64:
+		stringResult = fmt.Sprintf("%.0f", v)
+	case int:
+		stringResult = fmt.Sprintf("%d", v)
+	default:
 		return nil, errors.New("$phone requires a string")
 	}
 
-	return "+55 " + stringResult, nil
+	phone, valid := parsePhone(stringResult)
+	if !valid {
+		if dropInvalid {
+			return nil, nil
+		}
+		return stringResult, nil
+	}
+
+	formatted := formatPhone(phone, format)
+	if formatted == "" {
+		return nil, nil
+	}
+	return formatted, nil
+}
+
+// parsePhone normalizes brazilian numbers with DDD, with or without country code and trunk prefix.
+// numbers with another country code are only checked for the E.164 length
+func parsePhone(value string) (phoneNumber, bool) {
+	international := strings.HasPrefix(strings.TrimSpace(value), "+") || strings.HasPrefix(strings.TrimSpace(value), "00")
+
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := strings.TrimLeft(digits.String(), "0")
+
+	if international && !strings.HasPrefix(number, brazilCountryCode) {
+		if len(number) < 8 || len(number) > 15 {
+			return phoneNumber{}, false
+		}
+		return phoneNumber{number: number}, true
+	}
+
+	if (international || len(number) > 11) && strings.HasPrefix(number, brazilCountryCode) {
+		number = number[len(brazilCountryCode):]
+	}
+
+	if len(number) != 10 && len(number) != 11 {
+		return phoneNumber{}, false
+	}
+
+	ddd, subscriber := number[:2], number[2:]
+	if !validDDDs[ddd] {
+		return phoneNumber{}, false
+	}
+
+	switch {
+	case len(subscriber) == 9 && subscriber[0] == '9':
+		return phoneNumber{countryCode: brazilCountryCode, ddd: ddd, number: subscriber, mobile: true}, true
+	case len(subscriber) == 8 && subscriber[0] >= '2' && subscriber[0] <= '5':
+		return phoneNumber{countryCode: brazilCountryCode, ddd: ddd, number: subscriber}, true
+	case len(subscriber) == 8 && subscriber[0] >= '6':
+		// celulares antigos, sem o nono dígito
+		return phoneNumber{countryCode: brazilCountryCode, ddd: ddd, number: "9" + subscriber, mobile: true}, true
+	}
+	return phoneNumber{}, false
+}
+
+func formatPhone(phone phoneNumber, format string) string {
+	if phone.countryCode == "" {
+		if format == phoneType {
+			return ""
+		}
+		return "+" + phone.number
+	}
+
+	split := len(phone.number) - 4
+	switch format {
+	case phoneE164:
+		return "+" + phone.countryCode + phone.ddd + phone.number
+	case phoneNational:
+		return phone.ddd + phone.number
+	case phoneFormatted:
+		return fmt.Sprintf("(%s) %s-%s", phone.ddd, phone.number[:split], phone.number[split:])
+	case phoneType:
+		if phone.mobile {
+			return "MOVEL"
+		}
+		return "FIXO"
+	}
+	return fmt.Sprintf("+%s %s %s-%s", phone.countryCode, phone.ddd, phone.number[:split], phone.number[split:])
 }
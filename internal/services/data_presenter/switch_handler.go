@@ -2,10 +2,9 @@ package data_presenter
 
 import (
 	"errors"
-	"reflect"
 )
 
-// every $case can be a map of conditions, using OR logic. if more than one $case has a true condition, first $use in order is returned
+// every $case can be a map of conditions, using OR logic. conditions accept the operators of matchConditions. if more than one $case has a true condition, first $use in order is returned
 func handleSwitch(source map[string]any, location any) (any, error) {
 
 	mapLocation, isMap := location.(map[string]any)
@@ -52,17 +51,14 @@ func handleSwitch(source map[string]any, location any) (any, error) {
 		mapCase := caseKeyword.(map[string]any)
 		useValue := mapValue["$use"]
 
-		match := false
 		handler := NewKeywordHandler()
-		for key, condition := range mapCase {
-			result, err := handler.HandleKeywords(source, key)
-			if err != nil {
-				return nil, err
-			}
-			if reflect.DeepEqual(result, condition) {
-				match = true
-				break
-			}
+		match, err := matchConditions(mapCase, func(field string) (any, bool, error) {
+			// o resultado das keywords não distingue campo ausente de nulo, então um $case com null casa com os dois
+			result, err := handler.HandleKeywords(source, field)
+			return result, true, err
+		}, true)
+		if err != nil {
+			return nil, err
 		}
 
 		if !match {
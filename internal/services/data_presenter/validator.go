@@ -0,0 +1,446 @@
+package data_presenter
+
+import (
+	"export-service/internal/core/domain"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var conditionOperators = map[string]bool{
+	"$eq": true, "$ne": true, "$gt": true, "$gte": true, "$lt": true, "$lte": true,
+	"$in": true, "$nin": true, "$exists": true, "$regex": true, "$contains": true, "$not": true,
+}
+
+type specValidator struct {
+	handler *KeywordHandler
+	issues  []domain.PresentationSpecIssue
+}
+
+// ValidateSpec walks the spec without data, checking every keyword against the KeywordHandler registry
+// and the arguments each one requires, and that every key of the sheet options is in the spec.
+// crm specs have no sheet options, so keys of the spec without options are accepted. an empty result means the spec is valid
+func ValidateSpec(spec domain.PresentationSpecSpec, sheetOptions []domain.PresentationSpecSheetOptions) []domain.PresentationSpecIssue {
+	v := &specValidator{handler: NewKeywordHandler()}
+
+	optionKeys := make(map[string]bool, len(sheetOptions))
+	for i, option := range sheetOptions {
+		path := fmt.Sprintf("sheet_options[%d]", i)
+		if optionKeys[option.Key] {
+			v.addIssue(path, "duplicate key %s in sheet_options", option.Key)
+		}
+		optionKeys[option.Key] = true
+
+		if _, exists := spec[option.Key]; !exists {
+			v.addIssue(path, "key %s in sheet_options is not present in spec", option.Key)
+		}
+	}
+
+	keys := make([]string, 0, len(spec))
+	for key := range spec {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v.validateValue(key, spec[key])
+	}
+
+	return v.issues
+}
+
+func (v *specValidator) addIssue(path string, format string, args ...any) {
+	v.issues = append(v.issues, domain.PresentationSpecIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateValue checks a value the way Apply reads it: strings are props, maps are keywords or nested structures
+func (v *specValidator) validateValue(path string, value any) {
+	switch value := value.(type) {
+	case string:
+	case []any:
+		for i, item := range value {
+			v.validateValue(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	case map[string]any:
+		if keyword, isKeyword := v.getKeyword(path, value); isKeyword {
+			v.validateKeyword(joinPath(path, keyword), keyword, value[keyword])
+			return
+		}
+
+		for _, key := range sortedKeys(value) {
+			v.validateValue(joinPath(path, key), value[key])
+		}
+	default:
+		v.addIssue(path, "unsupported value of type %T, use $literal for constants", value)
+	}
+}
+
+// validateProp checks a value read with HandleKeywords, a prop or a single keyword map
+func (v *specValidator) validateProp(path string, value any) {
+	switch value := value.(type) {
+	case string:
+	case map[string]any:
+		keyword, isKeyword := v.getKeyword(path, value)
+		if !isKeyword {
+			v.addIssue(path, "expected a prop or a keyword")
+			return
+		}
+		v.validateKeyword(joinPath(path, keyword), keyword, value[keyword])
+	default:
+		v.addIssue(path, "expected a prop or a keyword, got %T", value)
+	}
+}
+
+func (v *specValidator) getKeyword(path string, value map[string]any) (string, bool) {
+	var keywords []string
+	for key := range value {
+		if strings.HasPrefix(key, "$") {
+			keywords = append(keywords, key)
+		}
+	}
+	if len(keywords) == 0 {
+		return "", false
+	}
+
+	sort.Strings(keywords)
+	if len(value) > 1 {
+		v.addIssue(path, "a keyword must be the only key of its map, found %s", strings.Join(keywords, ", "))
+	}
+	return keywords[0], true
+}
+
+func (v *specValidator) validateKeyword(path string, keyword string, arg any) {
+	if !v.handler.HasKeyword(keyword) {
+		v.addIssue(path, "no handler found for the keyword %s", keyword)
+		return
+	}
+
+	switch keyword {
+	case "$literal":
+	case "$fallback":
+		items, _ := v.requireArray(path, keyword, arg)
+		for i, item := range items {
+			v.validateProp(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	case "$flat":
+		items, isArray := v.requireArray(path, keyword, arg)
+		if isArray {
+			v.validateValue(path, items)
+		}
+	case "$joinby":
+		if args, isMap := v.requireMap(path, keyword, arg, "$prop"); isMap {
+			v.validateProp(joinPath(path, "$prop"), args["$prop"])
+			v.requireString(path, "$separator", args)
+		}
+	case "$for":
+		if args, isMap := v.requireMap(path, keyword, arg, "$prop", "$format"); isMap {
+			v.validateProp(joinPath(path, "$prop"), args["$prop"])
+			v.validateValue(joinPath(path, "$format"), args["$format"])
+			v.validateFilter(path, args)
+
+			_, hasIndex := args["$index"]
+			_, hasLimit := args["$limit"]
+			if hasIndex && hasLimit {
+				v.addIssue(path, "$limit and $index cant exist simultaneously in $for keyword")
+			}
+			v.requireInt(path, "$index", args)
+			v.requireInt(path, "$limit", args)
+		}
+	case "$template":
+		if args, isMap := v.requireMap(path, keyword, arg, "$format", "$variables"); isMap {
+			v.requireString(path, "$format", args)
+			v.requireString(path, "$for", args)
+			v.validateFilter(path, args)
+
+			variables, isMap := args["$variables"].(map[string]any)
+			if !isMap || len(variables) == 0 {
+				v.addIssue(joinPath(path, "$variables"), "$variables must be a non empty map")
+				return
+			}
+			for _, key := range sortedKeys(variables) {
+				v.validateProp(joinPath(joinPath(path, "$variables"), key), variables[key])
+			}
+		}
+	case "$compositestring":
+		if args, isMap := v.requireMap(path, keyword, arg); isMap {
+			for _, key := range sortedKeys(args) {
+				v.validateProp(joinPath(path, key), args[key])
+			}
+		}
+	case "$switch":
+		v.validateSwitch(path, arg)
+	case "$date":
+		v.validateOptions(path, arg, func(args map[string]any) {
+			if input, exists := args["$input"]; exists {
+				if _, isString := input.(string); !isString {
+					if _, isArray := input.([]any); !isArray {
+						v.addIssue(joinPath(path, "$input"), "$input must be a string or an array of strings")
+					}
+				}
+			}
+			v.requireString(path, "$output", args)
+			if timezone, isString := args["$timezone"].(string); isString {
+				if _, err := time.LoadLocation(timezone); err != nil {
+					v.addIssue(joinPath(path, "$timezone"), "invalid $timezone: %v", err)
+				}
+			} else {
+				v.requireString(path, "$timezone", args)
+			}
+		})
+	case "$phone":
+		v.validateOptions(path, arg, func(args map[string]any) {
+			if format, isString := args["$format"].(string); isString {
+				switch format {
+				case phoneInternational, phoneE164, phoneNational, phoneFormatted, phoneType:
+				default:
+					v.addIssue(joinPath(path, "$format"), "unknown $format %s in $phone", format)
+				}
+			} else {
+				v.requireString(path, "$format", args)
+			}
+			if dropInvalid, exists := args["$drop_invalid"]; exists {
+				if _, isBool := dropInvalid.(bool); !isBool {
+					v.addIssue(joinPath(path, "$drop_invalid"), "$drop_invalid must be a bool")
+				}
+			}
+		})
+	case "$round":
+		v.validateOptions(path, arg, func(args map[string]any) {
+			v.requireInt(path, "$decimals", args)
+		})
+	case "$sum", "$avg", "$min", "$max", "$count":
+		if items, isArray := arg.([]any); isArray {
+			for i, item := range items {
+				v.validateOperand(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+			return
+		}
+		v.validateOperand(path, arg)
+	case "$multiply", "$divide", "$percent":
+		if args, isMap := arg.(map[string]any); isMap && keyword == "$percent" {
+			if _, isMap := v.requireMap(path, keyword, args, "$value", "$total"); isMap {
+				v.validateOperand(joinPath(path, "$value"), args["$value"])
+				v.validateOperand(joinPath(path, "$total"), args["$total"])
+				v.requireInt(path, "$decimals", args)
+			}
+			return
+		}
+
+		items, isArray := v.requireArray(path, keyword, arg)
+		if !isArray {
+			return
+		}
+		if keyword == "$multiply" && len(items) < 2 {
+			v.addIssue(path, "$multiply requires at least two operands")
+		} else if keyword != "$multiply" && len(items) != 2 {
+			v.addIssue(path, "%s requires two operands", keyword)
+		}
+		for i, item := range items {
+			v.validateOperand(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	default:
+		v.validateProp(path, arg)
+	}
+}
+
+// validateOptions checks keywords taking a prop or a map with $prop and options
+func (v *specValidator) validateOptions(path string, arg any, validate func(args map[string]any)) {
+	if args, isMap := arg.(map[string]any); isMap {
+		if _, exists := args["$prop"]; exists {
+			v.validateProp(joinPath(path, "$prop"), args["$prop"])
+			validate(args)
+			return
+		}
+	}
+	v.validateProp(path, arg)
+}
+
+func (v *specValidator) validateOperand(path string, operand any) {
+	if _, isNumber := conditionNumber(operand); isNumber {
+		return
+	}
+	v.validateProp(path, operand)
+}
+
+func (v *specValidator) validateSwitch(path string, arg any) {
+	args, isMap := v.requireMap(path, "$switch", arg, "$cases")
+	if !isMap {
+		return
+	}
+
+	casesPath := joinPath(path, "$cases")
+	cases, isArray := args["$cases"].([]any)
+	if !isArray {
+		v.addIssue(casesPath, "$cases must be an array of maps")
+		return
+	}
+
+	for i, value := range cases {
+		casePath := fmt.Sprintf("%s[%d]", casesPath, i)
+		switchCase, isMap := v.requireMap(casePath, "$cases", value, "$case", "$use")
+		if !isMap {
+			continue
+		}
+
+		if conditions, isMap := switchCase["$case"].(map[string]any); isMap {
+			v.validateConditions(joinPath(casePath, "$case"), conditions)
+		} else if _, exists := switchCase["$case"]; exists {
+			v.addIssue(joinPath(casePath, "$case"), "every $case in $cases must be a map")
+		}
+		if useValue, exists := switchCase["$use"]; exists {
+			v.validateProp(joinPath(casePath, "$use"), useValue)
+		}
+	}
+}
+
+func (v *specValidator) validateFilter(path string, args map[string]any) {
+	filter, exists := args["$filter"]
+	if !exists {
+		return
+	}
+
+	conditions, isMap := filter.(map[string]any)
+	if !isMap {
+		v.addIssue(joinPath(path, "$filter"), "invalid data type for $filter keyword. must be a map")
+		return
+	}
+	v.validateConditions(joinPath(path, "$filter"), conditions)
+}
+
+// validateConditions checks the condition language of matchConditions
+func (v *specValidator) validateConditions(path string, conditions map[string]any) {
+	for _, key := range sortedKeys(conditions) {
+		keyPath := joinPath(path, key)
+		condition := conditions[key]
+
+		switch key {
+		case "$and", "$or":
+			items, isArray := condition.([]any)
+			if !isArray {
+				v.addIssue(keyPath, "%s requires an array of condition maps", key)
+				continue
+			}
+			for i, item := range items {
+				itemConditions, isMap := item.(map[string]any)
+				if !isMap {
+					v.addIssue(fmt.Sprintf("%s[%d]", keyPath, i), "%s requires an array of condition maps", key)
+					continue
+				}
+				v.validateConditions(fmt.Sprintf("%s[%d]", keyPath, i), itemConditions)
+			}
+		case "$not":
+			notConditions, isMap := condition.(map[string]any)
+			if !isMap {
+				v.addIssue(keyPath, "$not requires a condition map")
+				continue
+			}
+			v.validateConditions(keyPath, notConditions)
+		default:
+			if strings.HasPrefix(key, "$") {
+				v.addIssue(keyPath, "unknown condition operator %s", key)
+				continue
+			}
+			v.validateOperators(keyPath, condition)
+		}
+	}
+}
+
+func (v *specValidator) validateOperators(path string, condition any) {
+	operators, isMap := condition.(map[string]any)
+	if !isMap || !isOperatorMap(operators) {
+		return
+	}
+
+	for _, operator := range sortedKeys(operators) {
+		operatorPath := joinPath(path, operator)
+		operand := operators[operator]
+
+		if !conditionOperators[operator] {
+			v.addIssue(operatorPath, "unknown condition operator %s", operator)
+			continue
+		}
+
+		switch operator {
+		case "$in", "$nin":
+			if _, isArray := operand.([]any); !isArray {
+				v.addIssue(operatorPath, "%s requires an array", operator)
+			}
+		case "$exists":
+			if _, isBool := operand.(bool); !isBool {
+				v.addIssue(operatorPath, "$exists requires a boolean")
+			}
+		case "$regex":
+			pattern, isString := operand.(string)
+			if !isString {
+				v.addIssue(operatorPath, "$regex requires a string")
+			} else if _, err := compileRegex(pattern); err != nil {
+				v.addIssue(operatorPath, "%v", err)
+			}
+		case "$not":
+			v.validateOperators(operatorPath, operand)
+		}
+	}
+}
+
+// requireMap only returns true when the argument is a map with all the keys, so they can be validated
+func (v *specValidator) requireMap(path string, keyword string, arg any, keys ...string) (map[string]any, bool) {
+	args, isMap := arg.(map[string]any)
+	if !isMap {
+		v.addIssue(path, "%s requires a map", keyword)
+		return nil, false
+	}
+
+	complete := true
+	for _, key := range keys {
+		if _, exists := args[key]; !exists {
+			v.addIssue(path, "%s requires %s", keyword, key)
+			complete = false
+		}
+	}
+	return args, complete
+}
+
+func (v *specValidator) requireArray(path string, keyword string, arg any) ([]any, bool) {
+	items, isArray := arg.([]any)
+	if !isArray {
+		v.addIssue(path, "%s requires an array", keyword)
+	}
+	return items, isArray
+}
+
+func (v *specValidator) requireString(path string, key string, args map[string]any) {
+	value, exists := args[key]
+	if !exists {
+		return
+	}
+	if _, isString := value.(string); !isString {
+		v.addIssue(joinPath(path, key), "%s must be a string", key)
+	}
+}
+
+func (v *specValidator) requireInt(path string, key string, args map[string]any) {
+	value, exists := args[key]
+	if !exists {
+		return
+	}
+	if number, isNumber := conditionNumber(value); !isNumber || number != float64(int(number)) {
+		v.addIssue(joinPath(path, key), "%s must be an int", key)
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(value map[string]any) []string {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
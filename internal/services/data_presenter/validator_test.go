@@ -0,0 +1,159 @@
+package data_presenter_test
+
+import (
+	"encoding/json"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"export-service/internal/services/data_presenter"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func getSheetOptions(spec domain.PresentationSpecSpec) []domain.PresentationSpecSheetOptions {
+	var options []domain.PresentationSpecSheetOptions
+	for key := range spec {
+		options = append(options, domain.PresentationSpecSheetOptions{Key: key})
+	}
+	return options
+}
+
+func TestValidateSpec(t *testing.T) {
+	t.Run("Should accept every spec of the presenter tests", func(t *testing.T) {
+		for _, file := range []string{"testdata/test_presenter.yaml", "testdata/linkedin_test_presenter.yaml"} {
+			for _, tc := range getTestCases(file) {
+				issues := data_presenter.ValidateSpec(tc.SpecValue, getSheetOptions(tc.SpecValue))
+				assert.Empty(t, issues, tc.Name)
+			}
+		}
+	})
+
+	t.Run("Should accept the default mapping", func(t *testing.T) {
+		mappingBytes, err := os.ReadFile("../../../mapping.json")
+		require.NoError(t, err)
+
+		var body ports.PresentationSpecAddBody
+		require.NoError(t, json.Unmarshal(mappingBytes, &body))
+
+		assert.Empty(t, data_presenter.ValidateSpec(body.PresentationSpec, body.SpecOptions))
+	})
+
+	tests := []struct {
+		name     string
+		spec     string
+		expected []domain.PresentationSpecIssue
+	}{
+		{
+			name: "unknown keyword",
+			spec: `
+Tab1:
+  coluna:
+    $uppercase: razao_social`,
+			expected: []domain.PresentationSpecIssue{
+				{Path: "Tab1.coluna.$uppercase", Message: "no handler found for the keyword $uppercase"},
+			},
+		},
+		{
+			name: "missing sub keys",
+			spec: `
+Tab1:
+  coluna:
+    $for:
+      $prop: telefones
+  indice:
+    $for:
+      $prop: telefones
+      $format: telefone
+      $index: 1
+      $limit: 2
+  template:
+    $template:
+      $format: "{a}"`,
+			expected: []domain.PresentationSpecIssue{
+				{Path: "Tab1.coluna.$for", Message: "$for requires $format"},
+				{Path: "Tab1.indice.$for", Message: "$limit and $index cant exist simultaneously in $for keyword"},
+				{Path: "Tab1.template.$template", Message: "$template requires $variables"},
+			},
+		},
+		{
+			name: "nested keywords and conditions",
+			spec: `
+Tab1:
+  coluna:
+    $switch:
+      $cases:
+        - $case:
+            qtde_funcionarios:
+              $greater: 10
+          $use:
+            $upper:
+              $lower: [razao_social]
+  telefones:
+    $for:
+      $prop: telefones
+      $format:
+        numero:
+          $phone:
+            $prop: telefone_completo
+            $format: internacional
+      $filter:
+        telefone:
+          $regex: "("`,
+			expected: []domain.PresentationSpecIssue{
+				{Path: "Tab1.coluna.$switch.$cases[0].$case.qtde_funcionarios.$greater", Message: "unknown condition operator $greater"},
+				{Path: "Tab1.coluna.$switch.$cases[0].$use.$upper.$lower", Message: "expected a prop or a keyword, got []interface {}"},
+				{Path: "Tab1.telefones.$for.$format.numero.$phone.$format", Message: "unknown $format internacional in $phone"},
+				{Path: "Tab1.telefones.$for.$filter.telefone.$regex", Message: "invalid $regex \"(\": error parsing regexp: missing closing ): `(`"},
+			},
+		},
+		{
+			name: "constants outside $literal",
+			spec: `
+Tab1:
+  ativo: true
+  soma:
+    $divide: [capital_social]`,
+			expected: []domain.PresentationSpecIssue{
+				{Path: "Tab1.ativo", Message: "unsupported value of type bool, use $literal for constants"},
+				{Path: "Tab1.soma.$divide", Message: "$divide requires two operands"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run("Should report "+tc.name, func(t *testing.T) {
+			var spec domain.PresentationSpecSpec
+			require.NoError(t, yaml.Unmarshal([]byte(tc.spec), &spec))
+
+			assert.Equal(t, tc.expected, data_presenter.ValidateSpec(spec, getSheetOptions(spec)))
+		})
+	}
+
+	t.Run("Should report sheet options without spec", func(t *testing.T) {
+		spec := domain.PresentationSpecSpec{"Tab1": {"coluna": "razao_social"}}
+		options := []domain.PresentationSpecSheetOptions{{Key: "Tab1"}, {Key: "Tab2"}, {Key: "Tab1"}}
+
+		assert.Equal(t, []domain.PresentationSpecIssue{
+			{Path: "sheet_options[1]", Message: "key Tab2 in sheet_options is not present in spec"},
+			{Path: "sheet_options[2]", Message: "duplicate key Tab1 in sheet_options"},
+		}, data_presenter.ValidateSpec(spec, options))
+	})
+
+	t.Run("Should accept a crm spec without sheet options", func(t *testing.T) {
+		spec := domain.PresentationSpecSpec{
+			"company": {"entity": map[string]any{"name": "razao_social"}},
+			"deal":    {"entity": map[string]any{"title": "nome_fantasia"}},
+			"contacts": {
+				"$for": map[string]any{
+					"$prop":   "profiles",
+					"$format": map[string]any{"entity": map[string]any{"name": "nome"}},
+				},
+			},
+		}
+
+		assert.Empty(t, data_presenter.ValidateSpec(spec, nil))
+	})
+}
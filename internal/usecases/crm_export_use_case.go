@@ -2,10 +2,10 @@ package usecases
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"export-service/internal/core/domain"
 	"export-service/internal/core/ports"
+	"export-service/internal/readers"
 	"export-service/internal/repositories"
 	"export-service/internal/repositories/crm_company_repo"
 	"export-service/internal/repositories/crm_solicitation_repo"
@@ -13,6 +13,7 @@ import (
 	"export-service/internal/services/crm_exporter"
 	"export-service/internal/services/data_presenter"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -115,29 +116,24 @@ func (c *CrmExportUseCase) Execute(request CrmExportRequest, requestConfigs map[
 		c.solicitationRepo.UpdateStatus(context.Background(), crm_solicitation_repo.Interrupted, request.ListID, crm)
 		return err
 	}
+	c.logger.Info("Using presentation spec", zap.String("presentation_spec_id", spec.ID), zap.Int("presentation_spec_version", spec.Version))
 
-	downloadedData, err := c.downloadData(request)
+	_, err = c.solicitationRepo.UpdatePresentationSpec(context.Background(), spec.ID, spec.Version, request.ListID, crm)
 	if err != nil {
-		c.logError("Error when downloading data", err, request)
+		c.logError("Error when saving presentation spec version in solicitation", err, request)
 		c.solicitationRepo.UpdateStatus(context.Background(), crm_solicitation_repo.Interrupted, request.ListID, crm)
 		return err
 	}
 
-	presentedData, err := c.applyPresentationSpecCrm(request, downloadedData, spec)
+	records, err := c.downloadData(request)
 	if err != nil {
-		c.logError("Error when applying presentation spec", err, request)
-		c.solicitationRepo.UpdateStatus(context.Background(), crm_solicitation_repo.Interrupted, request.ListID, crm)
-		return err
-	}
-
-	presentedDataMappedToCnpjs, err := c.mapPresentedDataToCnpjs(downloadedData, presentedData)
-	if err != nil {
-		c.logError("Error mapping cnpj to presented data", err, request)
+		c.logError("Error when downloading data", err, request)
 		c.solicitationRepo.UpdateStatus(context.Background(), crm_solicitation_repo.Interrupted, request.ListID, crm)
 		return err
 	}
+	defer records.Close()
 
-	err = c.sendAllLeads(request, crmService, crmClient, presentedDataMappedToCnpjs, downloadedData, requestConfigs, solicitation)
+	err = c.sendAllLeads(request, crmService, crmClient, records, spec, requestConfigs, solicitation)
 
 	if err != nil {
 		c.solicitationRepo.UpdateStatus(context.Background(), crm_solicitation_repo.Interrupted, request.ListID, crm)
@@ -156,24 +152,16 @@ func (c *CrmExportUseCase) Execute(request CrmExportRequest, requestConfigs map[
 	// return url, nil
 }
 
-func (c *CrmExportUseCase) mapPresentedDataToCnpjs(data []map[string]any, presentedData []map[string]any) (map[any]map[string]any, error) {
-	presentedDataCnpjMap := make(map[any]map[string]any)
-	for key, item := range presentedData {
-		if len(data) != len(presentedData) {
-			return map[any]map[string]any{}, errors.New("data and presented data length mismatch")
-		}
-		cnpj, exists := data[key]["cnpj"]
-		if !exists || isZero(cnpj) {
-			cnpj, exists = data[key]["public_id"]
-			if !exists {
-				return map[any]map[string]any{}, errors.New("cnpj or public_id missing")
-			}
-		}
+func getLeadIdentifier(rawLead map[string]any) (string, error) {
+	if cnpj, ok := rawLead["cnpj"].(float64); ok && !isZero(cnpj) {
+		return fmt.Sprintf("%v", int(cnpj)), nil
+	}
 
-		presentedDataCnpjMap[cnpj] = item
+	if publicId, ok := rawLead["public_id"].(string); ok {
+		return publicId, nil
 	}
 
-	return presentedDataCnpjMap, nil
+	return "", errors.New("cnpj or public_id missing")
 }
 
 func isZero(value any) bool {
@@ -187,41 +175,48 @@ func isZero(value any) bool {
 	}
 }
 
-func (c *CrmExportUseCase) sendAllLeads(request CrmExportRequest, crmService crm_exporter.Crm, client any, leadsData map[any]map[string]any, rawLeadsData []map[string]any, configs map[string]any, solicitation crm_solicitation_repo.Solicitation) error {
+// leads are downloaded, presented and sent one at a time, so memory is bounded by a single lead
+func (c *CrmExportUseCase) sendAllLeads(request CrmExportRequest, crmService crm_exporter.Crm, client any, records *readers.JSONRecordReader, spec domain.PresentationSpec, configs map[string]any, solicitation crm_solicitation_repo.Solicitation) error {
 	current := solicitation.Current
 	sentCount := 0
 	leadIndex := 0
+	sentIdentifiers := make(map[string]struct{})
+
+	for {
+		rawLead, err := records.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			c.logError("Error when reading lead", err, request)
+			return err
+		}
+
+		stringIdentifier, err := getLeadIdentifier(rawLead)
+		if err != nil {
+			c.logError("Error getting lead identifier", err, request)
+			return err
+		}
+
+		if _, alreadySent := sentIdentifiers[stringIdentifier]; alreadySent {
+			continue
+		}
+		sentIdentifiers[stringIdentifier] = struct{}{}
 
-	for identifier, leadData := range leadsData {
 		if leadIndex < current {
 			leadIndex++
 			continue
 		}
 
-		var correspondingRawData map[string]any
-		var stringIdentifier string
-		is_cnpj := false
-		for _, rawLead := range rawLeadsData {
-			if rawCnpj, ok := rawLead["cnpj"]; ok && rawCnpj == identifier {
-				correspondingRawData = rawLead
-				is_cnpj = true
-				stringIdentifier = fmt.Sprintf("%v", int(identifier.(float64)))
-				break
-			}
-		}
-		if !is_cnpj {
-			for _, rawLead := range rawLeadsData {
-				if rawPublicId, ok := rawLead["public_id"]; ok && rawPublicId == identifier {
-					correspondingRawData = rawLead
-					stringIdentifier = identifier.(string)
-					break
-				}
-			}
+		leadData, err := c.applyPresentationSpecCrm(rawLead, spec)
+		if err != nil {
+			c.logError("Error when applying presentation spec", err, request)
+			return err
 		}
 
 		existingLead := solicitation.ExportedCompanies[stringIdentifier]
 		c.logInfoLead("Sending Lead", request, leadData)
-		leadResult, err := crmService.SendLead(client, leadData, correspondingRawData, configs, existingLead)
+		leadResult, err := crmService.SendLead(client, leadData, rawLead, configs, existingLead)
 		if err != nil {
 			return err
 		}
@@ -293,16 +288,14 @@ func (c *CrmExportUseCase) updateExportedLeadClickhouse(leadResult crm_exporter.
 	return nil
 }
 
-func (c *CrmExportUseCase) downloadData(request CrmExportRequest) ([]map[string]any, error) {
+func (c *CrmExportUseCase) downloadData(request CrmExportRequest) (*readers.JSONRecordReader, error) {
 	c.logInfo("Downloading data", request)
-	bytes, err := c.downloader.Download(request.DataDownloadURL)
+	body, err := c.downloader.Download(request.DataDownloadURL)
 	if err != nil {
 		return nil, err
 	}
 
-	var data []map[string]any
-	err = json.Unmarshal(bytes, &data)
-	return data, err
+	return readers.NewJSONRecordReader(body), nil
 }
 
 func (c *CrmExportUseCase) getPresentationSpec(request CrmExportRequest, crm string) (domain.PresentationSpec, error) {
@@ -316,24 +309,13 @@ func (c *CrmExportUseCase) getPresentationSpec(request CrmExportRequest, crm str
 	})
 }
 
-func (c *CrmExportUseCase) applyPresentationSpecCrm(request CrmExportRequest, data []map[string]any, spec domain.PresentationSpec) ([]map[string]any, error) {
-	c.logInfo("Applying presentation spec", request)
-	result := make([]map[string]any, 0)
-
-	for _, d := range data {
-		dd, err := data_presenter.PresentSingle(d, spec.Spec)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, dd)
-	}
-
-	return result, nil
+func (c *CrmExportUseCase) applyPresentationSpecCrm(data map[string]any, spec domain.PresentationSpec) (map[string]any, error) {
+	return data_presenter.PresentSingle(data, spec.Spec)
 }
 
 func (c *CrmExportUseCase) sendEmail(request CrmExportRequest, url string) error {
 	c.logInfo("Sending email", request)
-	return c.mailer.SendEmail(request.UserEmail, request.UserName, "d-b1c0014b01eb410a8c4b8112e4418a3f", url)
+	return c.mailer.SendEmail(request.UserEmail, request.UserName, "d-b1c0014b01eb410a8c4b8112e4418a3f", []string{url})
 }
 
 func (c *CrmExportUseCase) logInfo(message string, request CrmExportRequest) {
@@ -1,13 +1,16 @@
 package usecases
 
 type ExportRequest struct {
-	UserName        string `json:"user_name"`
-	UserEmail       string `json:"user_email"`
-	UserCompany     string `json:"user_company"`
-	DataSource      string `json:"source"`
-	DataDownloadURL string `json:"download_url"`
-	ListID          string `json:"list_id"`
-	ListName        string `json:"list_name"`
+	UserName          string `json:"user_name"`
+	UserEmail         string `json:"user_email"`
+	UserCompany       string `json:"user_company"`
+	DataSource        string `json:"source"`
+	DataDownloadURL   string `json:"download_url"`
+	ListID            string `json:"list_id"`
+	ListName          string `json:"list_name"`
+	Format            string `json:"format"`
+	ActiveColumnsOnly bool   `json:"active_columns_only"`
+	IncludeSummary    bool   `json:"include_summary"`
 }
 
 type CrmExportRequest struct {
@@ -27,3 +30,10 @@ type CrmExportHeaders struct {
 	OverwriteData bool   `json:"overwrite_data"`
 	CreateDeal    bool   `json:"create_deal"`
 }
+
+// ExportResult holds the files written and the version of the presentation spec used to write them
+type ExportResult struct {
+	DownloadUrls            []string `json:"download_urls"`
+	PresentationSpecID      string   `json:"presentation_spec_id"`
+	PresentationSpecVersion int      `json:"presentation_spec_version"`
+}
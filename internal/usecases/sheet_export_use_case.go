@@ -2,11 +2,17 @@ package usecases
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"export-service/internal/core/domain"
 	"export-service/internal/core/ports"
+	"export-service/internal/readers"
 	"export-service/internal/services/data_presenter"
+	"export-service/internal/writers"
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -14,7 +20,7 @@ import (
 )
 
 type SheetExportUseCase struct {
-	dataWriter           ports.DataWriter
+	dataWriters          map[string]ports.DataWriter
 	downloader           ports.Downloader
 	uploader             ports.Uploader
 	presentationSpecRepo ports.PresentationSpecRepository
@@ -22,9 +28,9 @@ type SheetExportUseCase struct {
 	logger               *zap.Logger
 }
 
-func NewSheetExportUseCase(dataWriter ports.DataWriter, downloader ports.Downloader, uploader ports.Uploader, presentationSpecRepo ports.PresentationSpecRepository, mailer ports.Mailer, logger *zap.Logger) *SheetExportUseCase {
+func NewSheetExportUseCase(dataWriters map[string]ports.DataWriter, downloader ports.Downloader, uploader ports.Uploader, presentationSpecRepo ports.PresentationSpecRepository, mailer ports.Mailer, logger *zap.Logger) *SheetExportUseCase {
 	return &SheetExportUseCase{
-		dataWriter:           dataWriter,
+		dataWriters:          dataWriters,
 		downloader:           downloader,
 		uploader:             uploader,
 		presentationSpecRepo: presentationSpecRepo,
@@ -33,56 +39,75 @@ func NewSheetExportUseCase(dataWriter ports.DataWriter, downloader ports.Downloa
 	}
 }
 
-func (s *SheetExportUseCase) Execute(request ExportRequest) (string, error) {
-	data, err := s.downloadData(request)
+// Execute returns the download url of every file written, exports too large for a single file are split in parts.
+// the result also records which version of the presentation spec was used
+func (s *SheetExportUseCase) Execute(request ExportRequest) (ExportResult, error) {
+	dataWriter, err := s.getDataWriter(request)
 	if err != nil {
-		s.logError("Error when downloading data", err, request)
-		return "", err
+		s.logError("Error when getting data writer", err, request)
+		return ExportResult{}, err
 	}
 
 	spec, err := s.getPresentationSpec(request)
 	if err != nil {
 		s.logError("Error when getting presentation spec", err, request)
-		return "", err
+		return ExportResult{}, err
 	}
+	s.logger.Info("Using presentation spec", zap.String("presentation_spec_id", spec.ID), zap.Int("presentation_spec_version", spec.Version))
 
-	presentedData, err := s.applyPresentationSpec(request, data, spec)
+	records, err := s.downloadData(request)
 	if err != nil {
-		s.logError("Error when applying presentation spec", err, request)
-		return "", err
+		s.logError("Error when downloading data", err, request)
+		return ExportResult{}, err
 	}
+	defer records.Close()
 
-	path, err := s.writeSheet(request, presentedData, spec)
+	paths, err := s.writeSheet(request, dataWriter, records, spec)
 	if err != nil {
 		s.logError("Error when writing data", err, request)
-		return "", err
+		return ExportResult{}, err
 	}
 
-	url, err := s.uploadSheet(request, path)
+	urls, err := s.uploadSheets(request, paths)
 	if err != nil {
 		s.logError("Error when uploading sheet", err, request)
-		return "", err
+		return ExportResult{}, err
 	}
 
-	err = s.sendEmail(request, url)
+	err = s.sendEmail(request, urls)
 	if err != nil {
 		s.logError("Error when sending email", err, request)
-		return "", err
+		return ExportResult{}, err
 	}
 
-	return url, nil
+	return ExportResult{
+		DownloadUrls:            urls,
+		PresentationSpecID:      spec.ID,
+		PresentationSpecVersion: spec.Version,
+	}, nil
 }
 
-func (s *SheetExportUseCase) downloadData(request ExportRequest) ([]map[string]any, error) {
+func (s *SheetExportUseCase) downloadData(request ExportRequest) (*readers.JSONRecordReader, error) {
 	s.logInfo("Downloading data", request)
-	bytes, err := s.downloader.Download(request.DataDownloadURL)
+	body, err := s.downloader.Download(request.DataDownloadURL)
 	if err != nil {
 		return nil, err
 	}
 
-	var data []map[string]any
-	err = json.Unmarshal(bytes, &data)
-	return data, err
+	return readers.NewJSONRecordReader(body), nil
+}
+
+func (s *SheetExportUseCase) getDataWriter(request ExportRequest) (ports.DataWriter, error) {
+	format := request.Format
+	if format == "" {
+		format = writers.XlsxFormat
+	}
+
+	dataWriter, exists := s.dataWriters[format]
+	if !exists {
+		return nil, errors.New("data writer for format " + format + " not found")
+	}
+	return dataWriter, nil
 }
 
 func (s *SheetExportUseCase) getPresentationSpec(request ExportRequest) (domain.PresentationSpec, error) {
@@ -96,34 +121,106 @@ func (s *SheetExportUseCase) getPresentationSpec(request ExportRequest) (domain.
 	})
 }
 
-func (s *SheetExportUseCase) applyPresentationSpec(request ExportRequest, data []map[string]any, spec domain.PresentationSpec) ([]map[string]any, error) {
-	s.logInfo("Applying presentation spec", request)
-	result := make([]map[string]any, 0)
+func (s *SheetExportUseCase) applyPresentationSpec(data map[string]any, spec domain.PresentationSpec) (map[string]any, error) {
+	return data_presenter.PresentSingle(data, spec.Spec)
+}
+
+// records are presented and written one at a time, so memory is bounded by a single record
+func (s *SheetExportUseCase) writeSheet(request ExportRequest, dataWriter ports.DataWriter, records *readers.JSONRecordReader, spec domain.PresentationSpec) (paths []string, err error) {
+	s.logInfo("Applying presentation spec and writing sheet", request)
+	rowWriter, err := dataWriter.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if abortErr := rowWriter.Abort(); abortErr != nil {
+			s.logError("Error discarding sheet files", abortErr, request)
+		}
+	}()
+
+	if request.IncludeSummary {
+		s.setSummary(request, rowWriter, spec)
+	}
+
+	if request.ActiveColumnsOnly {
+		rowWriter = writers.RestrictToActiveColumns(rowWriter, spec)
+	}
+
+	for {
+		record, err := records.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
 
-	for _, d := range data {
-		dd, err := data_presenter.PresentSingle(d, spec.Spec)
+		presented, err := s.applyPresentationSpec(record, spec)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, dd)
+
+		if err := rowWriter.WriteRow(presented); err != nil {
+			return nil, err
+		}
 	}
 
-	return result, nil
+	return rowWriter.Close()
 }
 
-func (s *SheetExportUseCase) writeSheet(request ExportRequest, data []map[string]any, spec domain.PresentationSpec) (string, error) {
-	s.logInfo("Writing sheet", request)
-	return s.dataWriter.Write(data, spec)
+func (s *SheetExportUseCase) setSummary(request ExportRequest, rowWriter ports.RowWriter, spec domain.PresentationSpec) {
+	summaryWriter, ok := rowWriter.(ports.SummaryRowWriter)
+	if !ok {
+		s.logInfo("Format has no summary sheet, skipping it", request)
+		return
+	}
+
+	summaryWriter.SetSummary(ports.ExportSummary{
+		ListName:                request.ListName,
+		DataSource:              request.DataSource,
+		UserName:                request.UserName,
+		UserEmail:               request.UserEmail,
+		PresentationSpecID:      spec.ID,
+		PresentationSpecVersion: spec.Version,
+		ExportedAt:              time.Now(),
+	})
 }
 
-func (s *SheetExportUseCase) uploadSheet(request ExportRequest, path string) (string, error) {
+func (s *SheetExportUseCase) uploadSheets(request ExportRequest, paths []string) ([]string, error) {
 	s.logInfo("Uploading sheet", request)
-	return s.uploader.Upload(fmt.Sprintf("(DRIVA %s) %s.xlsx", uuid.NewString()[:8], request.ListName), path)
+	id := uuid.NewString()[:8]
+	urls := make([]string, 0, len(paths))
+	for i, path := range paths {
+		name := fmt.Sprintf("(DRIVA %s) %s", id, request.ListName)
+		if len(paths) > 1 {
+			name = fmt.Sprintf("%s (%d de %d)", name, i+1, len(paths))
+		}
+
+		url, err := s.uploader.Upload(name+getFileExtension(path), path)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+// writers name their files "sheets.<format>", and formats such as jsonl.gz have more than one dot
+func getFileExtension(path string) string {
+	name := filepath.Base(path)
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[i:]
+	}
+	return ""
 }
 
-func (s *SheetExportUseCase) sendEmail(request ExportRequest, url string) error {
+// sendEmail sends a single email with the url of every part
+func (s *SheetExportUseCase) sendEmail(request ExportRequest, urls []string) error {
 	s.logInfo("Sending email", request)
-	return s.mailer.SendEmail(request.UserEmail, request.UserName, "d-b1c0014b01eb410a8c4b8112e4418a3f", url)
+	return s.mailer.SendEmail(request.UserEmail, request.UserName, "d-b1c0014b01eb410a8c4b8112e4418a3f", urls)
 }
 
 func (s *SheetExportUseCase) logInfo(message string, request ExportRequest) {
@@ -3,6 +3,7 @@ package usecases
 import (
 	"errors"
 	"export-service/internal/adapters"
+	"export-service/internal/readers"
 	"export-service/internal/repositories/presentation_spec_repo"
 	"export-service/internal/writers"
 	"fmt"
@@ -37,13 +38,17 @@ func TestSheetExportUseCase_downloadData(t *testing.T) {
 	p := presentation_spec_repo.NewPgPresentationSpecRepository(conn, zap.NewExample())
 	u := getS3Uploader(zap.NewExample())
 	m := adapters.NewDrivaMailer(zap.NewExample())
-	s := SheetExportUseCase{presentationSpecRepo: p, uploader: u, dataWriter: &writers.ExcelWriter{}, downloader: &adapters.HTTPDownloader{}, logger: zap.NewExample(), mailer: m}
+	s := SheetExportUseCase{presentationSpecRepo: p, uploader: u, dataWriters: writers.GetWriters(), downloader: &adapters.HTTPDownloader{}, logger: zap.NewExample(), mailer: m}
 	t.Run("Should download data", func(t *testing.T) {
 		r := ExportRequest{
 			DataDownloadURL: getTestURL(t, serveJSON),
 		}
 
-		data, err := s.downloadData(r)
+		records, err := s.downloadData(r)
+		require.NoError(t, err)
+		defer records.Close()
+
+		data, err := readRecords(records)
 		require.NoError(t, err)
 
 		assert.Lenf(t, data, 1, "Should have 1 company")
@@ -57,7 +62,11 @@ func TestSheetExportUseCase_downloadData(t *testing.T) {
 			DataDownloadURL: getTestURL(t, serveHTML),
 		}
 
-		_, err := s.downloadData(r)
+		records, err := s.downloadData(r)
+		require.NoError(t, err)
+		defer records.Close()
+
+		_, err = readRecords(records)
 		assert.Errorf(t, err, "Should fail if value is not an array")
 	})
 
@@ -85,6 +94,20 @@ func TestSheetExportUseCase_downloadData(t *testing.T) {
 	})
 }
 
+func readRecords(records *readers.JSONRecordReader) ([]map[string]any, error) {
+	var data []map[string]any
+	for {
+		record, err := records.Next()
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return data, err
+		}
+		data = append(data, record)
+	}
+}
+
 func serveJSON(w http.ResponseWriter, r *http.Request) {
 	file, err := os.Open("test_data/export_request.json")
 	if err != nil {
@@ -0,0 +1,64 @@
+package writers
+
+import (
+	"archive/zip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type archiveEntry struct {
+	name string
+	path string
+}
+
+// archiveFiles zips the given files, in order, into dir/sheets.zip
+func archiveFiles(dir string, entries []archiveEntry) (string, error) {
+	path := filepath.Join(dir, "sheets.zip")
+	archive, err := os.Create(path)
+	if err != nil {
+		log.Println("Error creating zip file", err)
+		return "", err
+	}
+	defer archive.Close()
+
+	zw := zip.NewWriter(archive)
+	for _, e := range entries {
+		entry, err := zw.Create(e.name)
+		if err != nil {
+			return "", err
+		}
+
+		src, err := os.Open(e.path)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(entry, src)
+		src.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		log.Println("Error saving zip file", err)
+		return "", err
+	}
+	return path, nil
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if strings.TrimSpace(name) == "" {
+		return "sheet"
+	}
+	return name
+}
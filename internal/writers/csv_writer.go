@@ -0,0 +1,174 @@
+package writers
+
+import (
+	"encoding/csv"
+	"errors"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// CSVWriter writes one delimited file per sheet option, zipping them together when more than one tab has data
+type CSVWriter struct {
+	Delimiter rune
+	Extension string
+}
+
+var _ ports.DataWriter = (*CSVWriter)(nil)
+
+func NewCSVWriter() *CSVWriter {
+	return &CSVWriter{Delimiter: ',', Extension: CsvFormat}
+}
+
+func NewTSVWriter() *CSVWriter {
+	return &CSVWriter{Delimiter: '\t', Extension: TsvFormat}
+}
+
+type csvRowWriter struct {
+	dir       string
+	delimiter rune
+	extension string
+	files     []*csvFile
+}
+
+type csvFile struct {
+	option domain.PresentationSpecSheetOptions
+	path   string
+	file   *os.File
+	writer *csv.Writer
+}
+
+var _ ports.RowWriter = (*csvRowWriter)(nil)
+
+func (c *CSVWriter) Open(spec domain.PresentationSpec) (ports.RowWriter, error) {
+	dir, err := os.MkdirTemp("", "sheets")
+	if err != nil {
+		log.Println("Error creating temp dir", err)
+		return nil, err
+	}
+
+	options := spec.GetOrderedSheetOptions()
+	files := make([]*csvFile, 0, len(options))
+	for i, sheetOption := range options {
+		sheetOption = expandSheetOption(sheetOption)
+		name := fmt.Sprintf("%d-%s.%s", i, sanitizeFileName(sheetOption.Key), c.Extension)
+		files = append(files, &csvFile{option: sheetOption, path: filepath.Join(dir, name)})
+	}
+
+	return &csvRowWriter{dir: dir, delimiter: c.Delimiter, extension: c.Extension, files: files}, nil
+}
+
+func (c *CSVWriter) Write(data []map[string]any, spec domain.PresentationSpec) ([]string, error) {
+	rowWriter, err := c.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range data {
+		if err := rowWriter.WriteRow(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return rowWriter.Close()
+}
+
+func (w *csvRowWriter) WriteRow(row map[string]any) error {
+	for _, f := range w.files {
+		values, ok := row[f.option.Key]
+		if !ok {
+			continue
+		}
+
+		if f.writer == nil {
+			if err := w.create(f); err != nil {
+				return err
+			}
+		}
+
+		for _, record := range getSheetRecords(values, f.option) {
+			line := make([]string, len(f.option.ActiveColumns))
+			for i, c := range f.option.ActiveColumns {
+				if value, ok := record[c]; ok {
+					line[i] = formatValue(value)
+				}
+			}
+
+			if err := f.writer.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// files are only created once their tab has a value, mirroring the sheets skipped by ExcelWriter
+func (w *csvRowWriter) create(f *csvFile) error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		log.Println("Error creating file", f.path, err)
+		return err
+	}
+
+	f.file = file
+	f.writer = csv.NewWriter(file)
+	f.writer.Comma = w.delimiter
+	return f.writer.Write(f.option.ActiveColumns)
+}
+
+func (w *csvRowWriter) Close() ([]string, error) {
+	var written []*csvFile
+	for _, f := range w.files {
+		if f.writer == nil {
+			continue
+		}
+
+		f.writer.Flush()
+		err := f.writer.Error()
+		if closeErr := f.file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Println("Error saving file", f.path, err)
+			return nil, err
+		}
+		written = append(written, f)
+	}
+
+	switch len(written) {
+	case 0:
+		return nil, errors.New("no sheet has data to be written")
+	case 1:
+		path := filepath.Join(w.dir, "sheets."+w.extension)
+		return []string{path}, os.Rename(written[0].path, path)
+	default:
+		path, err := w.zip(written)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+}
+
+func (w *csvRowWriter) Abort() error {
+	for _, f := range w.files {
+		if f.file != nil {
+			_ = f.file.Close()
+		}
+	}
+	return os.RemoveAll(w.dir)
+}
+
+func (w *csvRowWriter) zip(files []*csvFile) (string, error) {
+	entries := make([]archiveEntry, 0, len(files))
+	for _, f := range files {
+		entries = append(entries, archiveEntry{
+			name: fmt.Sprintf("%s.%s", sanitizeFileName(f.option.Key), w.extension),
+			path: f.path,
+		})
+	}
+	return archiveFiles(w.dir, entries)
+}
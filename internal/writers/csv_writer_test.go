@@ -0,0 +1,184 @@
+package writers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"export-service/internal/core/domain"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCSV(t *testing.T, path string, delimiter rune) [][]string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = delimiter
+	lines, err := reader.ReadAll()
+	require.NoError(t, err)
+	return lines
+}
+
+func TestCSVWriter_Write(t *testing.T) {
+	data := []map[string]any{{
+		"RFB": map[string]any{
+			"CNPJ":   "111111",
+			"Titulo": "RAZAO, SOCIAL",
+		},
+		"Telefones": []any{
+			map[string]any{"CNPJ": "111111", "Telefone": "123456"},
+			map[string]any{"CNPJ": "111111", "Telefone": "564565"},
+		},
+	}, {
+		"RFB": map[string]any{
+			"CNPJ": "222222",
+		},
+	}}
+
+	t.Run("Should write a single csv when only one tab has data", func(t *testing.T) {
+		spec := domain.PresentationSpec{
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{Key: "RFB", ActiveColumns: []string{"CNPJ", "Titulo"}, Position: 1},
+				{Key: "Socios", ActiveColumns: []string{"Nome"}, Position: 2, ShouldExplode: true},
+			},
+		}
+
+		paths, err := NewCSVWriter().Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+		assert.Equal(t, ".csv", filepath.Ext(path))
+
+		lines := readCSV(t, path, ',')
+		assert.Equal(t, [][]string{
+			{"CNPJ", "Titulo"},
+			{"111111", "RAZAO, SOCIAL"},
+			{"222222", ""},
+		}, lines)
+	})
+
+	t.Run("Should zip one file per tab", func(t *testing.T) {
+		spec := domain.PresentationSpec{
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{Key: "Telefones", ActiveColumns: []string{"CNPJ", "Telefone"}, Position: 2, ShouldExplode: true},
+				{Key: "RFB", ActiveColumns: []string{"CNPJ", "Titulo"}, Position: 1},
+			},
+		}
+
+		paths, err := NewTSVWriter().Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+		assert.Equal(t, ".zip", filepath.Ext(path))
+
+		archive, err := zip.OpenReader(path)
+		require.NoError(t, err)
+		defer archive.Close()
+
+		require.Len(t, archive.File, 2)
+		assert.Equal(t, "RFB.tsv", archive.File[0].Name)
+		assert.Equal(t, "Telefones.tsv", archive.File[1].Name)
+
+		entry, err := archive.File[1].Open()
+		require.NoError(t, err)
+		defer entry.Close()
+
+		reader := csv.NewReader(entry)
+		reader.Comma = '\t'
+		lines, err := reader.ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"CNPJ", "Telefone"},
+			{"111111", "123456"},
+			{"111111", "564565"},
+		}, lines)
+	})
+
+	t.Run("Should fail when no tab has data", func(t *testing.T) {
+		spec := domain.PresentationSpec{
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{Key: "Socios", ActiveColumns: []string{"Nome"}, Position: 1},
+			},
+		}
+
+		_, err := NewCSVWriter().Write(data, spec)
+		assert.Error(t, err)
+	})
+}
+
+func TestCSVWriter_MultiValues(t *testing.T) {
+	data := []map[string]any{{
+		"Contatos": map[string]any{
+			"CNPJ":      "111111",
+			"Email":     []any{"a@a.com", "b@b.com"},
+			"Telefones": []any{"123", "456", "789"},
+			"Sites":     []any{"a.com", "b.com"},
+			"Socios":    []any{"Ana", "Bia"},
+		},
+	}}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{
+				Key:           "Contatos",
+				ActiveColumns: []string{"CNPJ", "Email", "Telefones", "Sites", "Socios"},
+				Position:      1,
+				ColumnOptions: map[string]domain.PresentationSpecColumnOptions{
+					"Telefones": {MultiValue: domain.MultiValueSpread, MaxValues: 2},
+					"Sites":     {MultiValue: domain.MultiValueJoin, Separator: " | "},
+					"Socios":    {MultiValue: domain.MultiValueExplode},
+				},
+			},
+		},
+	}
+
+	paths, err := NewCSVWriter().Write(data, spec)
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	path := paths[0]
+
+	assert.Equal(t, [][]string{
+		{"CNPJ", "Email", "Telefones 1", "Telefones 2", "Sites", "Socios"},
+		{"111111", "a@a.com", "123", "456", "a.com | b.com", "Ana"},
+		{"111111", "a@a.com", "123", "456", "a.com | b.com", "Bia"},
+	}, readCSV(t, path, ','))
+}
+
+func TestCSVWriter_LargeNumbers(t *testing.T) {
+	// números vindos do json chegam como float64
+	data := []map[string]any{{
+		"RFB": map[string]any{
+			"CNPJ":           float64(12345678000195),
+			"Telefone":       float64(41999999999),
+			"Capital Social": float64(1234567.5),
+			"Telefones":      []any{float64(4133334444), float64(4199998888)},
+		},
+	}}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{
+				Key:           "RFB",
+				ActiveColumns: []string{"CNPJ", "Telefone", "Capital Social", "Telefones"},
+				Position:      1,
+				ColumnOptions: map[string]domain.PresentationSpecColumnOptions{
+					"Telefones": {MultiValue: domain.MultiValueJoin, Separator: ";"},
+				},
+			},
+		},
+	}
+
+	paths, err := NewCSVWriter().Write(data, spec)
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	assert.Equal(t, [][]string{
+		{"CNPJ", "Telefone", "Capital Social", "Telefones"},
+		{"12345678000195", "41999999999", "1234567.5", "4133334444;4199998888"},
+	}, readCSV(t, paths[0], ','))
+}
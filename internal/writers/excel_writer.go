@@ -6,131 +6,373 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/tealeg/xlsx/v3"
 )
 
+const excelMaxRows = 1_048_576
+
+// excel limits sheet names to 31 characters
+const excelMaxSheetName = 31
+
+// ExcelWriter continues a sheet on "Key (2)", "Key (3)"... once it has MaxRowsPerSheet rows and starts a new workbook
+// once the current one has MaxRowsPerWorkbook rows. zero means Excel's own row limit and no workbook limit
 type ExcelWriter struct {
+	MaxRowsPerSheet    int
+	MaxRowsPerWorkbook int
 }
 
 var _ ports.DataWriter = (*ExcelWriter)(nil)
 
-func (e *ExcelWriter) Write(data []map[string]any, spec domain.PresentationSpec) (string, error) {
-	wb := xlsx.NewFile()
+type excelRowWriter struct {
+	dir                string
+	options            []domain.PresentationSpecSheetOptions
+	maxRowsPerSheet    int
+	maxRowsPerWorkbook int
+	workbook           *excelWorkbook
+	paths              []string
+	summary            *ports.ExportSummary
+}
+
+type excelWorkbook struct {
+	wb            *xlsx.File
+	sheets        []*excelSheet // todas as abas, na ordem em que serão salvas
+	last          []*excelSheet // a aba sendo preenchida de cada sheet option
+	rows          int
+	rowsPerOption []int
+}
+
+type excelSheet struct {
+	sheet    *xlsx.Sheet
+	option   domain.PresentationSpecSheetOptions
+	index    int
+	part     int
+	hasValue bool
+}
+
+var _ ports.SummaryRowWriter = (*excelRowWriter)(nil)
+
+func (e *ExcelWriter) Open(spec domain.PresentationSpec) (ports.RowWriter, error) {
+	dir, err := os.MkdirTemp("", "sheets")
+	if err != nil {
+		log.Println("Error creating temp dir", err)
+		return nil, err
+	}
 
 	options := spec.GetOrderedSheetOptions()
+	expanded := make([]domain.PresentationSpecSheetOptions, 0, len(options))
 	for _, sheetOption := range options {
-		addSheet(data, wb, sheetOption)
+		expanded = append(expanded, expandSheetOption(sheetOption))
 	}
 
-	dir, err := os.MkdirTemp("", "sheets")
+	maxRowsPerSheet := e.MaxRowsPerSheet
+	if maxRowsPerSheet <= 0 || maxRowsPerSheet > excelMaxRows {
+		maxRowsPerSheet = excelMaxRows
+	}
+
+	w := &excelRowWriter{dir: dir, options: expanded, maxRowsPerSheet: maxRowsPerSheet, maxRowsPerWorkbook: e.MaxRowsPerWorkbook}
+	w.workbook = newExcelWorkbook(expanded)
+	return w, nil
+}
+
+func (e *ExcelWriter) Write(data []map[string]any, spec domain.PresentationSpec) ([]string, error) {
+	rowWriter, err := e.Open(spec)
 	if err != nil {
-		log.Println("Error creating temp dir", err)
-		return "", err
+		return nil, err
+	}
+
+	for _, d := range data {
+		if err := rowWriter.WriteRow(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return rowWriter.Close()
+}
+
+// sheets are created upfront to keep their order, the ones that never get a value are dropped before saving
+func newExcelWorkbook(options []domain.PresentationSpecSheetOptions) *excelWorkbook {
+	workbook := &excelWorkbook{wb: xlsx.NewFile(), last: make([]*excelSheet, len(options)), rowsPerOption: make([]int, len(options))}
+	for i, sheetOption := range options {
+		s, err := workbook.addSheet(sheetOption, i, 1)
+		if err != nil {
+			log.Println("Error adding sheet", sheetOption.Key, err)
+			continue
+		}
+		workbook.last[i] = s
+	}
+	return workbook
+}
+
+func (w *excelRowWriter) WriteRow(row map[string]any) error {
+	if w.maxRowsPerWorkbook > 0 && w.workbook.rows >= w.maxRowsPerWorkbook {
+		if err := w.rollWorkbook(); err != nil {
+			return err
+		}
+	}
+
+	for i, option := range w.options {
+		values, ok := row[option.Key] // verifica se o campo com os valores da aba atual existe
+		if !ok || w.workbook.last[i] == nil {
+			continue
+		}
+
+		w.workbook.last[i].hasValue = true
+		for _, record := range getSheetRecords(values, option) {
+			s, err := w.workbook.getSheetWithRoom(i, w.maxRowsPerSheet)
+			if err != nil {
+				return err
+			}
+
+			addRow(record, s.sheet, s.option)
+			w.workbook.rows++
+			w.workbook.rowsPerOption[i]++
+		}
+	}
+	return nil
+}
+
+func (w *excelRowWriter) SetSummary(summary ports.ExportSummary) {
+	w.summary = &summary
+}
+
+func (w *excelRowWriter) rollWorkbook() error {
+	if err := w.saveWorkbook(); err != nil {
+		return err
+	}
+	w.workbook = newExcelWorkbook(w.options)
+	return nil
+}
+
+func (w *excelRowWriter) Close() ([]string, error) {
+	if err := w.saveWorkbook(); err != nil {
+		return nil, err
+	}
+	return w.paths, nil
+}
+
+// Abort removes the workbooks already saved, the current one only lives in memory
+func (w *excelRowWriter) Abort() error {
+	return os.RemoveAll(w.dir)
+}
+
+func (w *excelRowWriter) saveWorkbook() error {
+	w.workbook.removeEmptySheets()
+
+	for _, s := range w.workbook.sheets {
+		formatSheet(s.sheet, s.option)
+	}
+
+	if w.summary != nil {
+		w.workbook.addSummarySheet(getSummaryRows(*w.summary, w.options, w.workbook.rowsPerOption))
 	}
 
-	path := fmt.Sprintf("%s/sheets.xlsx", dir)
-	err = wb.Save(path)
+	name := "sheets.xlsx"
+	if len(w.paths) > 0 {
+		name = fmt.Sprintf("sheets-%d.xlsx", len(w.paths)+1)
+	}
+
+	path := filepath.Join(w.dir, name)
+	err := w.workbook.wb.Save(path)
 	if err != nil {
 		log.Println("Error saving file", err)
-		return "", err
+		return err
 	}
-	return path, nil
+
+	w.paths = append(w.paths, path)
+	return nil
 }
 
-func addSheet(data []map[string]any, wb *xlsx.File, sheetOption domain.PresentationSpecSheetOptions) {
-	hasAtLeastOneValue := false
-	for _, d := range data {
-		_, ok := d[sheetOption.Key] // verifica se o campo com os valores da aba atual existe
-		if ok {
-			hasAtLeastOneValue = true
-			break
-		}
+// getSheetWithRoom returns the last sheet of the sheet option, adding an overflow sheet when it is full
+func (e *excelWorkbook) getSheetWithRoom(i int, maxRowsPerSheet int) (*excelSheet, error) {
+	last := e.last[i]
+	if last.sheet.MaxRow < maxRowsPerSheet {
+		return last, nil
 	}
 
-	if !hasAtLeastOneValue {
-		return
+	s, err := e.addSheet(last.option, i, last.part+1)
+	if err != nil {
+		log.Println("Error adding overflow sheet", last.option.Key, err)
+		return nil, err
 	}
-	sh, err := wb.AddSheet(sheetOption.Key)
+
+	s.hasValue = true
+	e.last[i] = s
+	return s, nil
+}
+
+func (e *excelWorkbook) addSheet(sheetOption domain.PresentationSpecSheetOptions, index int, part int) (*excelSheet, error) {
+	sh, err := e.wb.AddSheet(getSheetName(sheetOption.Key, part))
 	if err != nil {
-		log.Println("Error adding sheet", sheetOption.Key, err)
-		return
+		return nil, err
 	}
 
 	addHeaders(sh, sheetOption)
+	s := &excelSheet{sheet: sh, option: sheetOption, index: index, part: part}
+	e.sheets = append(e.sheets, s)
+	return s, nil
+}
 
-	insertData(data, sh, sheetOption)
+func (e *excelWorkbook) removeEmptySheets() {
+	sort.SliceStable(e.sheets, func(i, j int) bool { // abas de continuação ficam logo após a original
+		return e.sheets[i].index < e.sheets[j].index
+	})
 
-	for i := range sheetOption.ActiveColumns {
-		err = sh.SetColAutoWidth(i+1, xlsx.DefaultAutoWidth)
-		if err != nil {
-			log.Println("Error setting column width", err)
+	kept := make([]*excelSheet, 0, len(e.sheets))
+	e.wb.Sheets = make([]*xlsx.Sheet, 0, len(e.sheets))
+	for _, s := range e.sheets {
+		if !s.hasValue {
+			delete(e.wb.Sheet, s.sheet.Name)
+			continue
 		}
+
+		s.sheet.Selected = len(kept) == 0
+		kept = append(kept, s)
+		e.wb.Sheets = append(e.wb.Sheets, s.sheet)
 	}
+	e.sheets = kept
 }
 
-func insertData(data []map[string]any, sh *xlsx.Sheet, sheetOption domain.PresentationSpecSheetOptions) {
-	for _, d := range data {
-		values, ok := d[sheetOption.Key] // verifica se o campo com os valores da aba atual existe
-		if !ok {
-			continue
+func (e *excelWorkbook) addSummarySheet(rows [][]any) {
+	sh, err := e.wb.AddSheet(summarySheetName)
+	if err != nil {
+		log.Println("Error adding summary sheet", err)
+		return
+	}
+
+	for _, values := range rows {
+		row := sh.AddRow()
+		for i, value := range values {
+			cell := row.AddCell()
+			cell.SetValue(value)
+			if i == 0 {
+				cell.SetStyle(summaryLabelStyle)
+			} else {
+				cell.SetStyle(cellStyle)
+			}
 		}
+	}
 
-		if !sheetOption.ShouldExplode {
-			insertDataAsMap(values, sh, sheetOption)
-		} else {
-			insertDataAsList(values, sh, sheetOption)
+	for i := 1; i <= 2; i++ {
+		if err := sh.SetColAutoWidth(i, xlsx.DefaultAutoWidth); err != nil {
+			log.Println("Error setting column width", err)
 		}
 	}
 }
 
-func insertDataAsMap(values any, sh *xlsx.Sheet, sheetOption domain.PresentationSpecSheetOptions) {
-	if valMap, ok := values.(map[string]any); ok {
-		addRow(valMap, sh, sheetOption.ActiveColumns)
-	} else {
-		log.Println("Wrong type for key in map", sheetOption.Key)
+func getSheetName(key string, part int) string {
+	if part <= 1 {
+		return key
 	}
-}
 
-func insertDataAsList(values any, sh *xlsx.Sheet, sheetOption domain.PresentationSpecSheetOptions) {
-	if valList, ok := values.([]any); ok {
-		for _, v := range valList {
-			vMap, ok := v.(map[string]any)
-			if !ok {
-				log.Println("Wrong type for key inside list", sheetOption.Key)
-				continue
-			}
-			addRow(vMap, sh, sheetOption.ActiveColumns)
-		}
-	} else {
-		log.Println("Wrong type for key in list", sheetOption.Key)
+	suffix := fmt.Sprintf(" (%d)", part)
+	name := []rune(key)
+	if len(name)+len(suffix) > excelMaxSheetName {
+		name = name[:excelMaxSheetName-len(suffix)]
 	}
+	return string(name) + suffix
 }
 
-func addRow(data map[string]any, sh *xlsx.Sheet, activeColumns []string) {
+func addRow(data map[string]any, sh *xlsx.Sheet, sheetOption domain.PresentationSpecSheetOptions) {
 	row := sh.AddRow()
-	for _, c := range activeColumns {
+	for _, c := range sheetOption.ActiveColumns {
 		cell := row.AddCell() // adiciona célula mesmo que não tenha o valor para pular a coluna
 		if value, ok := data[c]; ok {
-			if valueAsList, ok := value.([]any); ok { // caso temporário pra corrigir email vindo como lista
-				value = ""
-				if len(valueAsList) > 0 {
-					value = valueAsList[0]
-				}
-			}
-			cell.Value = fmt.Sprintf("%v", value)
+			setCellValue(cell, value, sheetOption.ColumnOptions[c])
 		}
 		cell.SetStyle(cellStyle)
 	}
 }
 
+// setCellValue writes a typed cell, following the column type hint when there is one and the
+// presented Go type otherwise. values that don't fit the hinted type are written as text
+func setCellValue(cell *xlsx.Cell, value any, columnOptions domain.PresentationSpecColumnOptions) {
+	typed, columnType := getTypedValue(value, columnOptions)
+	switch columnType {
+	case domain.ColumnTypeNumber:
+		number := typed.(float64)
+		if format := getNumberFormat(number, columnOptions.NumberFormat); format != "" {
+			cell.SetFloatWithFormat(number, format)
+		} else {
+			cell.SetFloat(number)
+		}
+	case domain.ColumnTypeDate:
+		cell.SetDateWithOptions(typed.(time.Time), xlsx.DateTimeOptions{Location: time.UTC, ExcelTimeFormat: getDateFormat(columnOptions.NumberFormat)})
+	case domain.ColumnTypeBoolean:
+		cell.SetBool(typed.(bool))
+	case domain.ColumnTypeUrl:
+		cell.SetHyperlink(getLink(typed.(string)), typed.(string), "")
+	default:
+		cell.Value = typed.(string)
+	}
+}
+
 func addHeaders(sh *xlsx.Sheet, sheetOption domain.PresentationSpecSheetOptions) {
+	style := getHeaderStyle(sheetOption.HeaderColor)
 	headers := sh.AddRow()
 	headers.SetHeight(20)
 	for _, c := range sheetOption.ActiveColumns {
 		cell := headers.AddCell()
 		cell.Value = c
-		cell.SetStyle(headerStyle)
+		if label := sheetOption.ColumnOptions[c].Label; label != "" {
+			cell.Value = label
+		}
+		cell.SetStyle(style)
+	}
+}
+
+// getHeaderStyle copies headerStyle with the fill of the sheet, colors are accepted as RRGGBB, #RRGGBB or AARRGGBB
+func getHeaderStyle(color string) *xlsx.Style {
+	color = strings.ToUpper(strings.TrimPrefix(color, "#"))
+	switch len(color) {
+	case 6:
+		color = "FF" + color
+	case 8:
+	default:
+		if color != "" {
+			log.Println("Ignoring invalid header color", color)
+		}
+		return headerStyle
+	}
+
+	style := *headerStyle
+	style.Fill.FgColor = color
+	return &style
+}
+
+// formatSheet applies the column widths and visibility, the frozen header and the autofilter once every row is written
+func formatSheet(sh *xlsx.Sheet, sheetOption domain.PresentationSpecSheetOptions) {
+	for i, c := range sheetOption.ActiveColumns {
+		columnOptions := sheetOption.ColumnOptions[c]
+		if columnOptions.Width > 0 {
+			sh.SetColWidth(i+1, i+1, columnOptions.Width)
+		} else if err := sh.SetColAutoWidth(i+1, xlsx.DefaultAutoWidth); err != nil {
+			log.Println("Error setting column width", err)
+		}
+
+		if columnOptions.Hidden {
+			if col := sh.Col(i); col != nil {
+				hidden := true
+				col.Hidden = &hidden
+			}
+		}
+	}
+
+	if sheetOption.FreezeHeader {
+		sh.SheetViews = []xlsx.SheetView{{
+			Pane: &xlsx.Pane{YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft", State: "frozen"},
+		}}
+	}
+
+	if sheetOption.AutoFilter && len(sheetOption.ActiveColumns) > 0 {
+		sh.AutoFilter = &xlsx.AutoFilter{
+			TopLeftCell:     "A1",
+			BottomRightCell: xlsx.GetCellIDStringFromCoords(len(sheetOption.ActiveColumns)-1, sh.MaxRow-1),
+		}
 	}
 }
 
@@ -148,3 +390,8 @@ var headerStyle = &xlsx.Style{
 var cellStyle = &xlsx.Style{
 	Font: xlsx.Font{Color: "FF000000", Size: 11, Name: "Calibri", Family: 2},
 }
+
+var summaryLabelStyle = &xlsx.Style{
+	Font:      xlsx.Font{Color: "FF000000", Bold: true, Size: 11, Name: "Calibri", Family: 2},
+	ApplyFont: true,
+}
@@ -2,8 +2,13 @@ package writers
 
 import (
 	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,8 +54,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -122,8 +129,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -188,8 +197,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -233,8 +244,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -269,8 +282,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 		fmt.Println(path)
 
@@ -331,8 +346,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -372,8 +389,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -423,8 +442,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -480,8 +501,10 @@ func TestExcelWriter_Write(t *testing.T) {
 		}
 
 		ew := ExcelWriter{}
-		path, err := ew.Write(data, spec)
+		paths, err := ew.Write(data, spec)
 		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
 		assert.NotEmpty(t, path)
 
 		wb, err := xlsx.OpenFile(path)
@@ -493,7 +516,8 @@ func TestExcelWriter_Write(t *testing.T) {
 			assert.Equalf(t, expectedSheets[i], sh.Name, "Sheet %d should be %s", i, expectedSheets[i])
 		}
 
-		expectedValues := []string{"111111", "true", "123", "1.2"}
+		expectedValues := []string{"111111", "1", "123", "1.2"}
+		expectedTypes := []xlsx.CellType{xlsx.CellTypeString, xlsx.CellTypeBool, xlsx.CellTypeNumeric, xlsx.CellTypeNumeric}
 		err = wb.Sheet["RFB"].ForEachRow(func(r *xlsx.Row) error {
 			if r.GetCoordinate() == 0 {
 				assert.Equalf(t, "CNPJ", r.GetCell(0).Value, "Expected 'CNPJ' as first column header, got %s", r.GetCell(0).Value)
@@ -502,8 +526,332 @@ func TestExcelWriter_Write(t *testing.T) {
 
 			expected := expectedValues[r.GetCoordinate()-1]
 			assert.Equal(t, expected, r.GetCell(0).Value)
+			assert.Equal(t, expectedTypes[r.GetCoordinate()-1], r.GetCell(0).Type())
 			return nil
 		})
 		require.NoError(t, err)
 	})
+
+	t.Run("Should follow column type hints", func(t *testing.T) {
+		data := []map[string]any{{
+			"RFB": map[string]any{
+				"CNPJ":           float64(35965725000107),
+				"Capital Social": "110000.50",
+				"Abertura":       "10-01-2020",
+				"MEI":            "NÃO",
+				"Linkedin":       "linkedin.com/company/driva-tech",
+				"Telefone":       float64(41999999999),
+			},
+		}}
+
+		spec := domain.PresentationSpec{
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{
+					Key:           "RFB",
+					ActiveColumns: []string{"CNPJ", "Capital Social", "Abertura", "MEI", "Linkedin", "Telefone"},
+					Position:      1,
+					ColumnOptions: map[string]domain.PresentationSpecColumnOptions{
+						"Capital Social": {Type: domain.ColumnTypeNumber, NumberFormat: "#,##0.00"},
+						"Abertura":       {Type: domain.ColumnTypeDate, NumberFormat: "yyyy-mm-dd"},
+						"MEI":            {Type: domain.ColumnTypeBoolean},
+						"Linkedin":       {Type: domain.ColumnTypeUrl},
+						"Telefone":       {Type: domain.ColumnTypeString},
+					},
+				},
+			},
+		}
+
+		ew := ExcelWriter{}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+
+		wb, err := xlsx.OpenFile(path)
+		require.NoError(t, err)
+
+		row, err := wb.Sheet["RFB"].Row(1)
+		require.NoError(t, err)
+
+		assert.Equal(t, xlsx.CellTypeNumeric, row.GetCell(0).Type())
+		assert.Equal(t, "35965725000107", row.GetCell(0).Value)
+
+		assert.Equal(t, xlsx.CellTypeNumeric, row.GetCell(1).Type())
+		assert.Equal(t, "110000.5", row.GetCell(1).Value)
+		assert.Equal(t, "#,##0.00", row.GetCell(1).GetNumberFormat())
+
+		abertura, err := row.GetCell(2).GetTime(false)
+		require.NoError(t, err)
+		assert.Equal(t, "2020-01-10", abertura.Format("2006-01-02"))
+		assert.Equal(t, "yyyy-mm-dd", row.GetCell(2).GetNumberFormat())
+
+		assert.Equal(t, xlsx.CellTypeBool, row.GetCell(3).Type())
+		assert.False(t, row.GetCell(3).Bool())
+
+		assert.Equal(t, "https://linkedin.com/company/driva-tech", row.GetCell(4).Hyperlink.Link)
+		assert.Equal(t, "linkedin.com/company/driva-tech", row.GetCell(4).Value)
+
+		assert.Equal(t, xlsx.CellTypeString, row.GetCell(5).Type())
+		assert.Equal(t, "41999999999", row.GetCell(5).Value)
+	})
+}
+
+func TestExcelWriter_Formatting(t *testing.T) {
+	t.Run("Should apply sheet and column formatting options", func(t *testing.T) {
+		data := []map[string]any{
+			{"RFB": map[string]any{"CNPJ": "111111", "Capital Social": float64(1500), "Interno": "x"}},
+			{"RFB": map[string]any{"CNPJ": "222222", "Capital Social": float64(2500), "Interno": "y"}},
+		}
+
+		spec := domain.PresentationSpec{
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{
+					Key:           "RFB",
+					ActiveColumns: []string{"CNPJ", "Capital Social", "Interno"},
+					Position:      1,
+					HeaderColor:   "#00AA00",
+					FreezeHeader:  true,
+					AutoFilter:    true,
+					ColumnOptions: map[string]domain.PresentationSpecColumnOptions{
+						"CNPJ":           {Label: "Documento", Width: 30},
+						"Capital Social": {NumberFormat: "#,##0.00"},
+						"Interno":        {Hidden: true},
+					},
+				},
+			},
+		}
+
+		ew := ExcelWriter{}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+
+		wb, err := xlsx.OpenFile(path)
+		require.NoError(t, err)
+		sh := wb.Sheet["RFB"]
+
+		header, err := sh.Row(0)
+		require.NoError(t, err)
+		assert.Equal(t, "Documento", header.GetCell(0).Value)
+		assert.Equal(t, "Capital Social", header.GetCell(1).Value)
+		assert.Equal(t, "FF00AA00", header.GetCell(0).GetStyle().Fill.FgColor)
+
+		row, err := sh.Row(1)
+		require.NoError(t, err)
+		assert.Equal(t, "#,##0.00", row.GetCell(1).GetNumberFormat())
+
+		require.NotNil(t, sh.Col(0))
+		assert.Equal(t, float64(30), *sh.Col(0).Width)
+		require.NotNil(t, sh.Col(2))
+		require.NotNil(t, sh.Col(2).Hidden)
+		assert.True(t, *sh.Col(2).Hidden)
+
+		require.Len(t, sh.SheetViews, 1)
+		require.NotNil(t, sh.SheetViews[0].Pane)
+		assert.Equal(t, "frozen", sh.SheetViews[0].Pane.State)
+		assert.Equal(t, float64(1), sh.SheetViews[0].Pane.YSplit)
+
+		require.NotNil(t, sh.AutoFilter)
+		assert.Equal(t, "A1", sh.AutoFilter.TopLeftCell)
+		assert.Equal(t, "C3", sh.AutoFilter.BottomRightCell)
+	})
+}
+
+func TestExcelWriter_Overflow(t *testing.T) {
+	data := make([]map[string]any, 0, 4)
+	for i := range 4 {
+		cnpj := fmt.Sprintf("%d", i)
+		data = append(data, map[string]any{
+			"RFB": map[string]any{"CNPJ": cnpj},
+			"Contatos": []any{
+				map[string]any{"CNPJ": cnpj, "Email": "a@a.com"},
+				map[string]any{"CNPJ": cnpj, "Email": "b@b.com"},
+			},
+		})
+	}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+			{Key: "Contatos", ActiveColumns: []string{"CNPJ", "Email"}, Position: 2, ShouldExplode: true},
+		},
+	}
+
+	t.Run("Should continue full sheets on numbered sheets", func(t *testing.T) {
+		ew := ExcelWriter{MaxRowsPerSheet: 3}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+
+		wb, err := xlsx.OpenFile(paths[0])
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(wb.Sheets))
+		for _, sh := range wb.Sheets {
+			names = append(names, sh.Name)
+			assert.LessOrEqual(t, sh.MaxRow, 3)
+
+			header, err := sh.Row(0)
+			require.NoError(t, err)
+			assert.Equal(t, "CNPJ", header.GetCell(0).Value)
+		}
+		assert.Equal(t, []string{"RFB", "RFB (2)", "Contatos", "Contatos (2)", "Contatos (3)", "Contatos (4)"}, names)
+
+		row, err := wb.Sheet["RFB (2)"].Row(2)
+		require.NoError(t, err)
+		assert.Equal(t, "3", row.GetCell(0).Value)
+	})
+
+	t.Run("Should roll over to a new workbook", func(t *testing.T) {
+		ew := ExcelWriter{MaxRowsPerWorkbook: 6}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 2)
+		assert.Equal(t, "sheets.xlsx", filepath.Base(paths[0]))
+		assert.Equal(t, "sheets-2.xlsx", filepath.Base(paths[1]))
+
+		for i, path := range paths {
+			wb, err := xlsx.OpenFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, 3, wb.Sheet["RFB"].MaxRow)
+			assert.Equal(t, 5, wb.Sheet["Contatos"].MaxRow)
+
+			row, err := wb.Sheet["RFB"].Row(1)
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("%d", i*2), row.GetCell(0).Value)
+		}
+	})
+}
+
+func TestGetSheetName(t *testing.T) {
+	assert.Equal(t, "Contatos", getSheetName("Contatos", 1))
+	assert.Equal(t, "Contatos (2)", getSheetName("Contatos", 2))
+
+	name := getSheetName("Sócios e Administradores da Empresa", 12)
+	assert.Equal(t, "Sócios e Administradores d (12)", name)
+	assert.Len(t, []rune(name), excelMaxSheetName)
+}
+
+// BenchmarkExcelWriters compares the memory each xlsx writer holds with 200k rows written, before saving the file
+func BenchmarkExcelWriters(b *testing.B) {
+	const rowCount = 200_000
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ", "Titulo", "Capital Social", "Abertura", "Email"}, Position: 1},
+		},
+	}
+
+	row := func(i int) map[string]any {
+		return map[string]any{
+			"RFB": map[string]any{
+				"CNPJ":           float64(10000000000000 + i),
+				"Titulo":         fmt.Sprintf("EMPRESA %d LTDA", i),
+				"Capital Social": float64(i * 100),
+				"Abertura":       "10-01-2020",
+				"Email":          fmt.Sprintf("contato%d@empresa.com.br", i),
+			},
+		}
+	}
+
+	dataWriters := map[string]ports.DataWriter{
+		"ExcelWriter":          &ExcelWriter{},
+		"StreamingExcelWriter": &StreamingExcelWriter{},
+	}
+
+	for name, dataWriter := range dataWriters {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				runtime.GC()
+				var before runtime.MemStats
+				runtime.ReadMemStats(&before)
+
+				rowWriter, err := dataWriter.Open(spec)
+				require.NoError(b, err)
+				for i := range rowCount {
+					require.NoError(b, rowWriter.WriteRow(row(i)))
+				}
+
+				runtime.GC()
+				var after runtime.MemStats
+				runtime.ReadMemStats(&after)
+				b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "heap-B")
+
+				paths, err := rowWriter.Close()
+				require.NoError(b, err)
+				require.NoError(b, os.RemoveAll(filepath.Dir(paths[0])))
+			}
+		})
+	}
+}
+
+func TestExcelWriter_Summary(t *testing.T) {
+	data := []map[string]any{
+		{"RFB": map[string]any{"CNPJ": "111111"}, "Telefones": []any{map[string]any{"Telefone": "1"}, map[string]any{"Telefone": "2"}}},
+		{"RFB": map[string]any{"CNPJ": "222222"}},
+	}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 2, ShouldExplode: true},
+		},
+	}
+
+	summary := ports.ExportSummary{
+		ListName:                "Minha lista",
+		DataSource:              "empresas",
+		UserName:                "Maria",
+		UserEmail:               "maria@driva.io",
+		PresentationSpecID:      "123e4567-e89b-12d3-a456-426655440000",
+		PresentationSpecVersion: 3,
+		ExportedAt:              time.Date(2024, 5, 10, 14, 30, 0, 0, time.UTC),
+	}
+
+	t.Run("Should add the summary as the last sheet", func(t *testing.T) {
+		rowWriter, err := (&ExcelWriter{}).Open(spec)
+		require.NoError(t, err)
+		rowWriter.(ports.SummaryRowWriter).SetSummary(summary)
+
+		for _, d := range data {
+			require.NoError(t, rowWriter.WriteRow(d))
+		}
+		paths, err := rowWriter.Close()
+		require.NoError(t, err)
+
+		wb, err := xlsx.OpenFile(paths[0])
+		require.NoError(t, err)
+		require.Len(t, wb.Sheets, 3)
+		assert.Equal(t, summarySheetName, wb.Sheets[2].Name)
+
+		var lines [][]string
+		err = wb.Sheets[2].ForEachRow(func(r *xlsx.Row) error {
+			lines = append(lines, []string{r.GetCell(0).Value, r.GetCell(1).Value})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Lista", "Minha lista"},
+			{"Exportado em", "10/05/2024 14:30:00 UTC"},
+			{"Fonte dos dados", "empresas"},
+			{"Solicitado por", "Maria (maria@driva.io)"},
+			{"Presentation spec", "123e4567-e89b-12d3-a456-426655440000"},
+			{"Versão do presentation spec", "3"},
+			{"", ""},
+			{"Aba", "Linhas"},
+			{"RFB", "2"},
+			{"Telefones", "2"},
+		}, lines)
+	})
+
+	t.Run("Should not add the summary by default", func(t *testing.T) {
+		paths, err := (&ExcelWriter{}).Write(data, spec)
+		require.NoError(t, err)
+
+		wb, err := xlsx.OpenFile(paths[0])
+		require.NoError(t, err)
+		assert.Len(t, wb.Sheets, 2)
+	})
 }
@@ -0,0 +1,151 @@
+package writers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// JSONLWriter writes each presented record as is, one JSON object per line
+type JSONLWriter struct {
+	Gzip bool
+}
+
+var _ ports.DataWriter = (*JSONLWriter)(nil)
+
+type jsonlRowWriter struct {
+	path    string
+	file    *os.File
+	buffer  *bufio.Writer
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+}
+
+var _ ports.RowWriter = (*jsonlRowWriter)(nil)
+
+func (j *JSONLWriter) Open(spec domain.PresentationSpec) (ports.RowWriter, error) {
+	dir, err := os.MkdirTemp("", "sheets")
+	if err != nil {
+		log.Println("Error creating temp dir", err)
+		return nil, err
+	}
+
+	name := "sheets." + JsonlFormat
+	if j.Gzip {
+		name = "sheets." + JsonlGzipFormat
+	}
+
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		log.Println("Error creating file", path, err)
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	w := &jsonlRowWriter{path: path, file: file, buffer: bufio.NewWriter(file)}
+	var output io.Writer = w.buffer
+	if j.Gzip {
+		w.gzip = gzip.NewWriter(w.buffer)
+		output = w.gzip
+	}
+	w.encoder = json.NewEncoder(output)
+	w.encoder.SetEscapeHTML(false)
+
+	return w, nil
+}
+
+func (j *JSONLWriter) Write(data []map[string]any, spec domain.PresentationSpec) ([]string, error) {
+	rowWriter, err := j.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range data {
+		if err := rowWriter.WriteRow(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return rowWriter.Close()
+}
+
+func (w *jsonlRowWriter) WriteRow(row map[string]any) error {
+	return w.encoder.Encode(row)
+}
+
+func (w *jsonlRowWriter) Close() ([]string, error) {
+	var err error
+	if w.gzip != nil {
+		err = w.gzip.Close()
+	}
+	if flushErr := w.buffer.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Println("Error saving file", w.path, err)
+		return nil, err
+	}
+	return []string{w.path}, nil
+}
+
+func (w *jsonlRowWriter) Abort() error {
+	_ = w.file.Close()
+	return os.RemoveAll(filepath.Dir(w.path))
+}
+
+type activeColumnsRowWriter struct {
+	ports.RowWriter
+	options []domain.PresentationSpecSheetOptions
+}
+
+// RestrictToActiveColumns drops every presented key that is not a sheet option and
+// every field that is not one of its ActiveColumns before handing the row to rowWriter
+func RestrictToActiveColumns(rowWriter ports.RowWriter, spec domain.PresentationSpec) ports.RowWriter {
+	return &activeColumnsRowWriter{RowWriter: rowWriter, options: spec.GetOrderedSheetOptions()}
+}
+
+func (w *activeColumnsRowWriter) WriteRow(row map[string]any) error {
+	restricted := make(map[string]any, len(w.options))
+	for _, option := range w.options {
+		values, ok := row[option.Key]
+		if !ok {
+			continue
+		}
+
+		switch v := values.(type) {
+		case map[string]any:
+			restricted[option.Key] = keepColumns(v, option.ActiveColumns)
+		case []any:
+			list := make([]any, 0, len(v))
+			for _, item := range v {
+				if itemMap, isMap := item.(map[string]any); isMap {
+					list = append(list, keepColumns(itemMap, option.ActiveColumns))
+				}
+			}
+			restricted[option.Key] = list
+		default:
+			restricted[option.Key] = v
+		}
+	}
+	return w.RowWriter.WriteRow(restricted)
+}
+
+func keepColumns(values map[string]any, columns []string) map[string]any {
+	kept := make(map[string]any, len(columns))
+	for _, c := range columns {
+		if value, ok := values[c]; ok {
+			kept[c] = value
+		}
+	}
+	return kept
+}
@@ -0,0 +1,96 @@
+package writers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"export-service/internal/core/domain"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readJSONL(t *testing.T, r io.Reader) []map[string]any {
+	var records []map[string]any
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestJSONLWriter_Write(t *testing.T) {
+	data := []map[string]any{{
+		"RFB": map[string]any{"CNPJ": "111111", "Titulo": "RAZAO SOCIAL"},
+		"Telefones": []any{
+			map[string]any{"Telefone": "123456", "WhatsApp": "SIM"},
+		},
+		"Extra": "not a sheet",
+	}, {
+		"RFB": map[string]any{"CNPJ": "222222"},
+	}}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 2, ShouldExplode: true},
+		},
+	}
+
+	t.Run("Should write one presented record per line", func(t *testing.T) {
+		ew := JSONLWriter{}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+		assert.True(t, strings.HasSuffix(path, ".jsonl"))
+
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		records := readJSONL(t, file)
+		require.Len(t, records, 2)
+		assert.Equal(t, "not a sheet", records[0]["Extra"])
+		assert.Equal(t, "RAZAO SOCIAL", records[0]["RFB"].(map[string]any)["Titulo"])
+	})
+
+	t.Run("Should gzip and restrict to active columns", func(t *testing.T) {
+		ew := JSONLWriter{Gzip: true}
+		rowWriter, err := ew.Open(spec)
+		require.NoError(t, err)
+
+		rowWriter = RestrictToActiveColumns(rowWriter, spec)
+		for _, d := range data {
+			require.NoError(t, rowWriter.WriteRow(d))
+		}
+		paths, err := rowWriter.Close()
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+		assert.True(t, strings.HasSuffix(path, ".jsonl.gz"))
+
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		gz, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		defer gz.Close()
+
+		records := readJSONL(t, gz)
+		require.Len(t, records, 2)
+		assert.Equal(t, map[string]any{
+			"RFB":       map[string]any{"CNPJ": "111111"},
+			"Telefones": []any{map[string]any{"Telefone": "123456"}},
+		}, records[0])
+		assert.Equal(t, map[string]any{"RFB": map[string]any{"CNPJ": "222222"}}, records[1])
+	})
+}
@@ -0,0 +1,291 @@
+package writers
+
+import (
+	"errors"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const parquetRowsPerRowGroup = 50_000
+
+type parquetColumnType int
+
+const (
+	parquetString parquetColumnType = iota
+	parquetNumber
+	parquetDate
+)
+
+// ParquetWriter writes one snappy compressed Parquet file per sheet option, typing the
+// columns mapped with $number and $date in the presentation spec
+type ParquetWriter struct {
+}
+
+var _ ports.DataWriter = (*ParquetWriter)(nil)
+
+type parquetRowWriter struct {
+	dir   string
+	files []*parquetFile
+}
+
+type parquetFile struct {
+	option      domain.PresentationSpecSheetOptions
+	columnTypes []parquetColumnType
+	schema      *parquet.Schema
+	path        string
+	file        *os.File
+	writer      *parquet.Writer
+}
+
+var _ ports.RowWriter = (*parquetRowWriter)(nil)
+
+func (p *ParquetWriter) Open(spec domain.PresentationSpec) (ports.RowWriter, error) {
+	dir, err := os.MkdirTemp("", "sheets")
+	if err != nil {
+		log.Println("Error creating temp dir", err)
+		return nil, err
+	}
+
+	options := spec.GetOrderedSheetOptions()
+	files := make([]*parquetFile, 0, len(options))
+	for i, sheetOption := range options {
+		sheetOption = expandSheetOption(sheetOption)
+		columnTypes := getParquetColumnTypes(spec.Spec[sheetOption.Key], sheetOption)
+		files = append(files, &parquetFile{
+			option:      sheetOption,
+			columnTypes: columnTypes,
+			schema:      buildParquetSchema(sheetOption, columnTypes),
+			path:        filepath.Join(dir, fmt.Sprintf("%d-%s.parquet", i, sanitizeFileName(sheetOption.Key))),
+		})
+	}
+
+	return &parquetRowWriter{dir: dir, files: files}, nil
+}
+
+func (p *ParquetWriter) Write(data []map[string]any, spec domain.PresentationSpec) ([]string, error) {
+	rowWriter, err := p.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range data {
+		if err := rowWriter.WriteRow(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return rowWriter.Close()
+}
+
+func (w *parquetRowWriter) WriteRow(row map[string]any) error {
+	for _, f := range w.files {
+		values, ok := row[f.option.Key]
+		if !ok {
+			continue
+		}
+
+		if f.writer == nil {
+			file, err := os.Create(f.path)
+			if err != nil {
+				log.Println("Error creating file", f.path, err)
+				return err
+			}
+			f.file = file
+			f.writer = parquet.NewWriter(file, f.schema, parquet.Compression(&parquet.Snappy), parquet.MaxRowsPerRowGroup(parquetRowsPerRowGroup))
+		}
+
+		records := getSheetRecords(values, f.option)
+		rows := make([]parquet.Row, 0, len(records))
+		for _, record := range records {
+			rows = append(rows, f.buildRow(record))
+		}
+
+		if _, err := f.writer.WriteRows(rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *parquetRowWriter) Close() ([]string, error) {
+	var written []*parquetFile
+	for _, f := range w.files {
+		if f.writer == nil {
+			continue
+		}
+
+		err := f.writer.Close()
+		if closeErr := f.file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Println("Error saving file", f.path, err)
+			return nil, err
+		}
+		written = append(written, f)
+	}
+
+	switch len(written) {
+	case 0:
+		return nil, errors.New("no sheet has data to be written")
+	case 1:
+		path := filepath.Join(w.dir, "sheets.parquet")
+		return []string{path}, os.Rename(written[0].path, path)
+	default:
+		entries := make([]archiveEntry, 0, len(written))
+		for _, f := range written {
+			entries = append(entries, archiveEntry{name: sanitizeFileName(f.option.Key) + ".parquet", path: f.path})
+		}
+		path, err := archiveFiles(w.dir, entries)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+}
+
+func (w *parquetRowWriter) Abort() error {
+	for _, f := range w.files {
+		if f.file != nil {
+			_ = f.file.Close()
+		}
+	}
+	return os.RemoveAll(w.dir)
+}
+
+func (f *parquetFile) buildRow(record map[string]any) parquet.Row {
+	row := make(parquet.Row, len(f.option.ActiveColumns))
+	for i, c := range f.option.ActiveColumns {
+		value, ok := toParquetValue(record[c], f.columnTypes[i])
+		if !ok {
+			row[i] = parquet.NullValue().Level(0, 0, i)
+			continue
+		}
+		row[i] = value.Level(0, 1, i)
+	}
+	return row
+}
+
+func toParquetValue(value any, columnType parquetColumnType) (parquet.Value, bool) {
+	if value == nil {
+		return parquet.Value{}, false
+	}
+
+	switch columnType {
+	case parquetNumber:
+		number, ok := toFloat(value)
+		if !ok {
+			return parquet.Value{}, false
+		}
+		return parquet.DoubleValue(number), true
+	case parquetDate:
+		date, ok := toDate(value)
+		if !ok {
+			return parquet.Value{}, false
+		}
+		return parquet.Int32Value(int32(date.Unix() / 86400)), true
+	default:
+		return parquet.ByteArrayValue([]byte(formatValue(value))), true
+	}
+}
+
+func buildParquetSchema(sheetOption domain.PresentationSpecSheetOptions, columnTypes []parquetColumnType) *parquet.Schema {
+	fields := make([]parquet.Field, len(sheetOption.ActiveColumns))
+	group := make(parquet.Group, len(sheetOption.ActiveColumns))
+	for i, c := range sheetOption.ActiveColumns {
+		var node parquet.Node
+		switch columnTypes[i] {
+		case parquetNumber:
+			node = parquet.Leaf(parquet.DoubleType)
+		case parquetDate:
+			node = parquet.Date()
+		default:
+			node = parquet.String()
+		}
+		node = parquet.Optional(node)
+		group[c] = node
+		fields[i] = parquetColumn{Node: node, name: c}
+	}
+
+	return parquet.NewSchema(sanitizeFileName(sheetOption.Key), parquetColumns{Group: group, fields: fields})
+}
+
+// parquetColumns keeps the ActiveColumns order, parquet.Group would sort the columns by name
+type parquetColumns struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func (p parquetColumns) Fields() []parquet.Field { return p.fields }
+
+type parquetColumn struct {
+	parquet.Node
+	name string
+}
+
+func (p parquetColumn) Name() string { return p.name }
+
+func (p parquetColumn) Value(base reflect.Value) reflect.Value {
+	return base.MapIndex(reflect.ValueOf(p.name))
+}
+
+// getParquetColumnTypes uses the column type hint when there is one, otherwise the keyword each
+// active column is mapped with in the spec of its sheet
+func getParquetColumnTypes(tabSpec any, sheetOption domain.PresentationSpecSheetOptions) []parquetColumnType {
+	columnSpecs := make(map[string]any)
+	collectColumnSpecs(tabSpec, columnSpecs)
+
+	columnTypes := make([]parquetColumnType, len(sheetOption.ActiveColumns))
+	for i, c := range sheetOption.ActiveColumns {
+		switch sheetOption.ColumnOptions[c].Type {
+		case domain.ColumnTypeNumber:
+			columnTypes[i] = parquetNumber
+			continue
+		case domain.ColumnTypeDate:
+			columnTypes[i] = parquetDate
+			continue
+		}
+
+		mapSpec, isMap := columnSpecs[c].(map[string]any)
+		if !isMap {
+			continue
+		}
+
+		if _, exists := mapSpec["$number"]; exists {
+			columnTypes[i] = parquetNumber
+		} else if _, exists := mapSpec["$date"]; exists {
+			columnTypes[i] = parquetDate
+		}
+	}
+	return columnTypes
+}
+
+func collectColumnSpecs(tabSpec any, columnSpecs map[string]any) {
+	mapSpec, isMap := tabSpec.(map[string]any)
+	if !isMap {
+		return
+	}
+
+	if forSpec, exists := mapSpec["$for"].(map[string]any); exists {
+		collectColumnSpecs(forSpec["$format"], columnSpecs)
+		return
+	}
+
+	if flatSpec, exists := mapSpec["$flat"].([]any); exists {
+		for _, item := range flatSpec {
+			collectColumnSpecs(item, columnSpecs)
+		}
+		return
+	}
+
+	for column, columnSpec := range mapSpec {
+		columnSpecs[column] = columnSpec
+	}
+}
@@ -0,0 +1,102 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetWriter_Write(t *testing.T) {
+	t.Run("Should write typed columns in active columns order", func(t *testing.T) {
+		data := []map[string]any{{
+			"RFB": map[string]any{
+				"Titulo":         "RAZAO SOCIAL",
+				"Capital Social": float64(110000),
+				"Abertura":       "10-01-2020",
+			},
+		}, {
+			"RFB": map[string]any{
+				"Titulo":         "RAZAO SOCIAL 222",
+				"Capital Social": "not a number",
+			},
+		}}
+
+		spec := domain.PresentationSpec{
+			Spec: domain.PresentationSpecSpec{
+				"RFB": {
+					"Titulo":         "razao_social",
+					"Capital Social": map[string]any{"$number": "capital_social"},
+					"Abertura":       map[string]any{"$date": "data_inicio_atividade"},
+				},
+			},
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{Key: "RFB", ActiveColumns: []string{"Titulo", "Capital Social", "Abertura"}, Position: 1},
+			},
+		}
+
+		ew := ParquetWriter{}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+		assert.Equal(t, ".parquet", filepath.Ext(path))
+
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		reader := parquet.NewReader(file)
+		defer reader.Close()
+
+		columns := reader.Schema().Fields()
+		require.Len(t, columns, 3)
+		assert.Equal(t, "Titulo", columns[0].Name())
+		assert.Equal(t, "Capital Social", columns[1].Name())
+		assert.Equal(t, "Abertura", columns[2].Name())
+		assert.Equal(t, parquet.Double, columns[1].Type().Kind())
+		assert.Equal(t, parquet.Int32, columns[2].Type().Kind())
+
+		rows := make([]parquet.Row, 2)
+		n, err := reader.ReadRows(rows)
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+		}
+		require.Equal(t, 2, n)
+
+		assert.Equal(t, "RAZAO SOCIAL", rows[0][0].String())
+		assert.Equal(t, float64(110000), rows[0][1].Double())
+		expectedDate := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, int32(expectedDate.Unix()/86400), rows[0][2].Int32())
+
+		assert.True(t, rows[1][1].IsNull())
+		assert.True(t, rows[1][2].IsNull())
+	})
+
+	t.Run("Should zip one file per sheet option", func(t *testing.T) {
+		data := []map[string]any{{
+			"RFB":       map[string]any{"CNPJ": "111111"},
+			"Telefones": []any{map[string]any{"Telefone": "123456"}},
+		}}
+
+		spec := domain.PresentationSpec{
+			SheetOptions: []domain.PresentationSpecSheetOptions{
+				{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+				{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 2, ShouldExplode: true},
+			},
+		}
+
+		ew := ParquetWriter{}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		path := paths[0]
+		assert.Equal(t, ".zip", filepath.Ext(path))
+	})
+}
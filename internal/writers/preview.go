@@ -0,0 +1,59 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"time"
+)
+
+const previewDateLayout = "2006-01-02"
+
+// PreviewSheets flattens presented rows into the sheets ExcelWriter would write, without creating a file.
+// sheets without values are kept, so the headers of the whole spec can be reviewed
+func PreviewSheets(rows []map[string]any, spec domain.PresentationSpec) []ports.SheetPreview {
+	options := spec.GetOrderedSheetOptions()
+	sheets := make([]ports.SheetPreview, 0, len(options))
+	for _, option := range options {
+		option = expandSheetOption(option)
+
+		sheet := ports.SheetPreview{Key: option.Key, Headers: make([]string, 0, len(option.ActiveColumns)), Rows: [][]any{}}
+		for _, c := range option.ActiveColumns {
+			label := c
+			if columnLabel := option.ColumnOptions[c].Label; columnLabel != "" {
+				label = columnLabel
+			}
+			sheet.Headers = append(sheet.Headers, label)
+		}
+
+		for _, row := range rows {
+			values, ok := row[option.Key]
+			if !ok {
+				continue
+			}
+
+			for _, record := range getSheetRecords(values, option) {
+				sheet.Rows = append(sheet.Rows, getPreviewRow(record, option))
+			}
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets
+}
+
+func getPreviewRow(record map[string]any, option domain.PresentationSpecSheetOptions) []any {
+	row := make([]any, 0, len(option.ActiveColumns))
+	for _, c := range option.ActiveColumns {
+		value, ok := record[c]
+		if !ok {
+			row = append(row, nil)
+			continue
+		}
+
+		typed, columnType := getTypedValue(value, option.ColumnOptions[c])
+		if columnType == domain.ColumnTypeDate {
+			typed = typed.(time.Time).Format(previewDateLayout)
+		}
+		row = append(row, typed)
+	}
+	return row
+}
@@ -0,0 +1,51 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewSheets(t *testing.T) {
+	rows := []map[string]any{{
+		"Empresa": map[string]any{
+			"CNPJ":     float64(35965725000107),
+			"Abertura": "2020-01-10",
+			"Emails":   []any{"a@driva.io", "b@driva.io"},
+		},
+		"Telefones": []any{
+			map[string]any{"Telefone": "123456"},
+			map[string]any{"Telefone": "654321"},
+		},
+	}}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 2, ShouldExplode: true},
+			{
+				Key:           "Empresa",
+				ActiveColumns: []string{"CNPJ", "Abertura", "Emails", "Site"},
+				Position:      1,
+				ColumnOptions: map[string]domain.PresentationSpecColumnOptions{
+					"Abertura": {Type: domain.ColumnTypeDate, Label: "Data de Abertura"},
+					"Emails":   {MultiValue: domain.MultiValueSpread, MaxValues: 2},
+				},
+			},
+			{Key: "Socios", ActiveColumns: []string{"Nome"}, Position: 3},
+		},
+	}
+
+	sheets := PreviewSheets(rows, spec)
+	require.Len(t, sheets, 3)
+
+	assert.Equal(t, "Empresa", sheets[0].Key)
+	assert.Equal(t, []string{"CNPJ", "Data de Abertura", "Emails 1", "Emails 2", "Site"}, sheets[0].Headers)
+	assert.Equal(t, [][]any{{float64(35965725000107), "2020-01-10", "a@driva.io", "b@driva.io", nil}}, sheets[0].Rows)
+
+	assert.Equal(t, [][]any{{"123456"}, {"654321"}}, sheets[1].Rows)
+
+	assert.Equal(t, "Socios", sheets[2].Key)
+	assert.Empty(t, sheets[2].Rows)
+}
@@ -0,0 +1,344 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	XlsxFormat       = "xlsx"
+	XlsxStreamFormat = "xlsx-stream"
+	CsvFormat        = "csv"
+	TsvFormat        = "tsv"
+	ParquetFormat    = "parquet"
+	JsonlFormat      = "jsonl"
+	JsonlGzipFormat  = "jsonl.gz"
+)
+
+// row limits of the xlsx writers, vars so the tests reach them with a few rows. ExcelWriter keeps the whole
+// workbook in memory, so it rolls over much sooner. the streaming writer continues on "Key (2)" sheets first
+var (
+	excelRowsPerSheet             = excelMaxRows
+	excelRowsPerWorkbook          = 200_000
+	streamingExcelRowsPerWorkbook = 2 * excelMaxRows
+)
+
+func GetWriters() map[string]ports.DataWriter {
+	return map[string]ports.DataWriter{
+		XlsxFormat:       &ExcelWriter{MaxRowsPerSheet: excelRowsPerSheet, MaxRowsPerWorkbook: excelRowsPerWorkbook},
+		XlsxStreamFormat: &StreamingExcelWriter{MaxRowsPerSheet: excelRowsPerSheet, MaxRowsPerWorkbook: streamingExcelRowsPerWorkbook},
+		CsvFormat:        NewCSVWriter(),
+		TsvFormat:        NewTSVWriter(),
+		ParquetFormat:    &ParquetWriter{},
+		JsonlFormat:      &JSONLWriter{},
+		JsonlGzipFormat:  &JSONLWriter{Gzip: true},
+	}
+}
+
+// getSheetRecords returns the records of a sheet key in a presented row, one for each output line
+func getSheetRecords(values any, sheetOption domain.PresentationSpecSheetOptions) []map[string]any {
+	if !sheetOption.ShouldExplode {
+		valMap, ok := values.(map[string]any)
+		if !ok {
+			log.Println("Wrong type for key in map", sheetOption.Key)
+			return nil
+		}
+		return applyMultiValues(valMap, sheetOption)
+	}
+
+	valList, ok := values.([]any)
+	if !ok {
+		log.Println("Wrong type for key in list", sheetOption.Key)
+		return nil
+	}
+
+	records := make([]map[string]any, 0, len(valList))
+	for _, v := range valList {
+		vMap, ok := v.(map[string]any)
+		if !ok {
+			log.Println("Wrong type for key inside list", sheetOption.Key)
+			continue
+		}
+		records = append(records, applyMultiValues(vMap, sheetOption)...)
+	}
+	return records
+}
+
+const defaultSeparator = ", "
+
+const defaultMaxValues = 3
+
+// expandSheetOption replaces each spread column by its numbered columns (Email 1, Email 2...), which share its options
+func expandSheetOption(sheetOption domain.PresentationSpecSheetOptions) domain.PresentationSpecSheetOptions {
+	expanded := sheetOption
+	expanded.ActiveColumns = make([]string, 0, len(sheetOption.ActiveColumns))
+	expanded.ColumnOptions = make(map[string]domain.PresentationSpecColumnOptions, len(sheetOption.ColumnOptions))
+	for c, columnOptions := range sheetOption.ColumnOptions {
+		expanded.ColumnOptions[c] = columnOptions
+	}
+
+	for _, c := range sheetOption.ActiveColumns {
+		columnOptions := sheetOption.ColumnOptions[c]
+		if columnOptions.MultiValue != domain.MultiValueSpread {
+			expanded.ActiveColumns = append(expanded.ActiveColumns, c)
+			continue
+		}
+
+		for i := 1; i <= getMaxValues(columnOptions); i++ {
+			name := getSpreadColumnName(c, i)
+			expanded.ActiveColumns = append(expanded.ActiveColumns, name)
+			if _, exists := expanded.ColumnOptions[name]; exists {
+				continue
+			}
+
+			spreadOptions := columnOptions
+			spreadOptions.MultiValue = domain.MultiValueFirst
+			if columnOptions.Label != "" {
+				spreadOptions.Label = getSpreadColumnName(columnOptions.Label, i)
+			}
+			expanded.ColumnOptions[name] = spreadOptions
+		}
+	}
+	return expanded
+}
+
+// applyMultiValues writes the columns presented as lists following their multi value policy,
+// returning more than one record when a column is exploded into rows
+func applyMultiValues(record map[string]any, sheetOption domain.PresentationSpecSheetOptions) []map[string]any {
+	var applied map[string]any
+	var explodedColumns []string
+	for c, value := range record {
+		list, isList := value.([]any)
+		if !isList {
+			continue
+		}
+
+		if applied == nil { // só copia o registro quando há alguma lista
+			applied = make(map[string]any, len(record))
+			for k, v := range record {
+				applied[k] = v
+			}
+		}
+
+		columnOptions := sheetOption.ColumnOptions[c]
+		switch columnOptions.MultiValue {
+		case domain.MultiValueJoin:
+			applied[c] = joinValues(list, columnOptions.Separator)
+		case domain.MultiValueSpread:
+			for i, v := range list {
+				if i >= getMaxValues(columnOptions) {
+					break
+				}
+				applied[getSpreadColumnName(c, i+1)] = v
+			}
+		case domain.MultiValueExplode:
+			explodedColumns = append(explodedColumns, c)
+		default:
+			applied[c] = getFirstValue(list)
+		}
+	}
+
+	if applied == nil {
+		return []map[string]any{record}
+	}
+
+	sort.Strings(explodedColumns)
+	records := []map[string]any{applied}
+	for _, c := range explodedColumns {
+		records = explodeColumn(records, c)
+	}
+	return records
+}
+
+func explodeColumn(records []map[string]any, column string) []map[string]any {
+	exploded := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		list, _ := record[column].([]any)
+		if len(list) == 0 {
+			record[column] = ""
+			exploded = append(exploded, record)
+			continue
+		}
+
+		for _, v := range list {
+			row := make(map[string]any, len(record))
+			for k, value := range record {
+				row[k] = value
+			}
+			row[column] = v
+			exploded = append(exploded, row)
+		}
+	}
+	return exploded
+}
+
+func getFirstValue(list []any) any {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[0]
+}
+
+func joinValues(list []any, separator string) string {
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		values = append(values, formatValue(v))
+	}
+	return strings.Join(values, separator)
+}
+
+func getMaxValues(columnOptions domain.PresentationSpecColumnOptions) int {
+	if columnOptions.MaxValues > 0 {
+		return columnOptions.MaxValues
+	}
+	return defaultMaxValues
+}
+
+func getSpreadColumnName(column string, i int) string {
+	return fmt.Sprintf("%s %d", column, i)
+}
+
+const defaultDateFormat = "dd/mm/yyyy"
+
+const integerFormat = "0"
+
+// getTypedValue converts a presented value to the column type hint, or to the type of the presented Go value
+// when there is none. values that don't fit the type are returned as text with ColumnTypeString
+func getTypedValue(value any, columnOptions domain.PresentationSpecColumnOptions) (any, string) {
+	columnType := columnOptions.Type
+	if columnType == "" {
+		columnType = getColumnType(value)
+	}
+
+	switch columnType {
+	case domain.ColumnTypeNumber:
+		if number, ok := toFloat(value); ok {
+			return number, columnType
+		}
+	case domain.ColumnTypeDate:
+		if date, ok := toDate(value); ok {
+			return date, columnType
+		}
+	case domain.ColumnTypeBoolean:
+		if b, ok := toBool(value); ok {
+			return b, columnType
+		}
+	case domain.ColumnTypeUrl:
+		if link, ok := value.(string); ok && link != "" {
+			return link, columnType
+		}
+	}
+	return formatValue(value), domain.ColumnTypeString
+}
+
+func getColumnType(value any) string {
+	switch v := value.(type) {
+	case int, int64, float32, float64:
+		return domain.ColumnTypeNumber
+	case bool:
+		return domain.ColumnTypeBoolean
+	case time.Time:
+		return domain.ColumnTypeDate
+	case string:
+		if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+			return domain.ColumnTypeUrl
+		}
+	}
+	return domain.ColumnTypeString
+}
+
+func getNumberFormat(number float64, format string) string {
+	if format == "" && number == math.Trunc(number) {
+		return integerFormat // evita que números grandes, como CNPJs, virem notação científica
+	}
+	return format
+}
+
+func getDateFormat(format string) string {
+	if format == "" {
+		return defaultDateFormat
+	}
+	return format
+}
+
+func getLink(link string) string {
+	if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+		return "https://" + link
+	}
+	return link
+}
+
+// formatValue writes floats without exponent, json numbers like cnpjs and phones decode as float64
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// dates are presented by $date as DD-MM-YYYY, the other layouts cover raw values passed through
+var presentedDateLayouts = []string{"02-01-2006", "2006-01-02", "2006-01-02T15:04:05Z", time.RFC3339}
+
+func toDate(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range presentedDateLayouts {
+			if date, err := time.Parse(layout, v); err == nil {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch v {
+		case "SIM", "Sim", "sim":
+			return true, true
+		case "NÃO", "Não", "não", "NAO", "Nao", "nao":
+			return false, true
+		}
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,83 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestRowWriter_Abort(t *testing.T) {
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 2, ShouldExplode: true},
+		},
+	}
+	row := map[string]any{
+		"RFB":       map[string]any{"CNPJ": "111111"},
+		"Telefones": []any{map[string]any{"Telefone": "123456"}},
+	}
+
+	for format, dataWriter := range GetWriters() {
+		t.Run("Should remove the files written by "+format, func(t *testing.T) {
+			tempDir := t.TempDir()
+			t.Setenv("TMPDIR", tempDir)
+
+			rowWriter, err := dataWriter.Open(spec)
+			require.NoError(t, err)
+			require.NoError(t, rowWriter.WriteRow(row))
+			require.NoError(t, rowWriter.Abort())
+
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestGetWriters_Overflow(t *testing.T) {
+	defaultRowsPerSheet := excelRowsPerSheet
+	excelRowsPerSheet = 3
+	t.Cleanup(func() { excelRowsPerSheet = defaultRowsPerSheet })
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+			{Key: "Contatos", ActiveColumns: []string{"CNPJ", "Email"}, Position: 2, ShouldExplode: true},
+		},
+	}
+	data := make([]map[string]any, 0, 4)
+	for i := range 4 {
+		cnpj := fmt.Sprintf("%d", i)
+		data = append(data, map[string]any{
+			"RFB": map[string]any{"CNPJ": cnpj},
+			"Contatos": []any{
+				map[string]any{"CNPJ": cnpj, "Email": "a@a.com"},
+				map[string]any{"CNPJ": cnpj, "Email": "b@b.com"},
+			},
+		})
+	}
+
+	for _, format := range []string{XlsxFormat, XlsxStreamFormat} {
+		t.Run("Should continue full sheets on numbered sheets in "+format, func(t *testing.T) {
+			rowWriter, err := GetWriters()[format].Open(spec)
+			require.NoError(t, err)
+			for _, row := range data {
+				require.NoError(t, rowWriter.WriteRow(row))
+			}
+			paths, err := rowWriter.Close()
+			require.NoError(t, err)
+			require.Len(t, paths, 1)
+
+			f, err := excelize.OpenFile(paths[0])
+			require.NoError(t, err)
+			defer f.Close()
+			assert.Equal(t, []string{"RFB", "RFB (2)", "Contatos", "Contatos (2)", "Contatos (3)", "Contatos (4)"}, f.GetSheetList())
+		})
+	}
+}
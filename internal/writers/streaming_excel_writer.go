@@ -0,0 +1,629 @@
+package writers
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3"
+	"github.com/xuri/excelize/v2"
+)
+
+// columns without a width can't be measured before their rows are streamed, so they get at least this width
+const streamingMinColumnWidth = 15
+
+// StreamingExcelWriter writes the same workbooks as ExcelWriter, but flushes the rows to disk as they are written
+// instead of keeping the whole workbook in memory
+type StreamingExcelWriter struct {
+	MaxRowsPerSheet    int
+	MaxRowsPerWorkbook int
+}
+
+var _ ports.DataWriter = (*StreamingExcelWriter)(nil)
+
+type streamingExcelRowWriter struct {
+	dir                string
+	options            []domain.PresentationSpecSheetOptions
+	maxRowsPerSheet    int
+	maxRowsPerWorkbook int
+	workbook           *streamingWorkbook
+	paths              []string
+	summary            *ports.ExportSummary
+}
+
+type streamingWorkbook struct {
+	f             *excelize.File
+	sheets        []*streamingSheet
+	last          []*streamingSheet
+	rows          int
+	rowsPerOption []int
+	cellStyle     int
+	styles        map[string]int // estilos de célula por formato de número
+}
+
+type streamingSheet struct {
+	name     string
+	option   domain.PresentationSpecSheetOptions
+	index    int
+	part     int
+	stream   *excelize.StreamWriter // só é aberto quando a aba recebe o primeiro valor
+	rows     int
+	links    int
+	hasValue bool
+}
+
+var _ ports.SummaryRowWriter = (*streamingExcelRowWriter)(nil)
+
+func (e *StreamingExcelWriter) Open(spec domain.PresentationSpec) (ports.RowWriter, error) {
+	dir, err := os.MkdirTemp("", "sheets")
+	if err != nil {
+		log.Println("Error creating temp dir", err)
+		return nil, err
+	}
+
+	options := spec.GetOrderedSheetOptions()
+	expanded := make([]domain.PresentationSpecSheetOptions, 0, len(options))
+	for _, sheetOption := range options {
+		expanded = append(expanded, expandSheetOption(sheetOption))
+	}
+
+	maxRowsPerSheet := e.MaxRowsPerSheet
+	if maxRowsPerSheet <= 0 || maxRowsPerSheet > excelMaxRows {
+		maxRowsPerSheet = excelMaxRows
+	}
+
+	workbook, err := newStreamingWorkbook(expanded)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &streamingExcelRowWriter{
+		dir:                dir,
+		options:            expanded,
+		maxRowsPerSheet:    maxRowsPerSheet,
+		maxRowsPerWorkbook: e.MaxRowsPerWorkbook,
+		workbook:           workbook,
+	}, nil
+}
+
+func (e *StreamingExcelWriter) Write(data []map[string]any, spec domain.PresentationSpec) ([]string, error) {
+	rowWriter, err := e.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range data {
+		if err := rowWriter.WriteRow(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return rowWriter.Close()
+}
+
+// sheets are created upfront to keep their order, the ones that never get a value are dropped before saving
+func newStreamingWorkbook(options []domain.PresentationSpecSheetOptions) (*streamingWorkbook, error) {
+	f := excelize.NewFile()
+	cellStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "000000", Size: 11, Family: "Calibri"}})
+	if err != nil {
+		log.Println("Error creating cell style", err)
+		return nil, err
+	}
+
+	workbook := &streamingWorkbook{
+		f:             f,
+		last:          make([]*streamingSheet, len(options)),
+		rowsPerOption: make([]int, len(options)),
+		cellStyle:     cellStyle,
+		styles:        make(map[string]int),
+	}
+	for i, sheetOption := range options {
+		s, err := workbook.addSheet(sheetOption, i, 1)
+		if err != nil {
+			log.Println("Error adding sheet", sheetOption.Key, err)
+			continue
+		}
+		workbook.last[i] = s
+	}
+	return workbook, nil
+}
+
+func (w *streamingExcelRowWriter) WriteRow(row map[string]any) error {
+	if w.maxRowsPerWorkbook > 0 && w.workbook.rows >= w.maxRowsPerWorkbook {
+		if err := w.rollWorkbook(); err != nil {
+			return err
+		}
+	}
+
+	for i, option := range w.options {
+		values, ok := row[option.Key] // verifica se o campo com os valores da aba atual existe
+		if !ok || w.workbook.last[i] == nil {
+			continue
+		}
+
+		w.workbook.last[i].hasValue = true
+		for _, record := range getSheetRecords(values, option) {
+			s, err := w.workbook.getSheetWithRoom(i, w.maxRowsPerSheet)
+			if err != nil {
+				return err
+			}
+
+			if err := w.workbook.addRow(record, s); err != nil {
+				return err
+			}
+			w.workbook.rows++
+			w.workbook.rowsPerOption[i]++
+		}
+	}
+	return nil
+}
+
+func (w *streamingExcelRowWriter) SetSummary(summary ports.ExportSummary) {
+	w.summary = &summary
+}
+
+func (w *streamingExcelRowWriter) rollWorkbook() error {
+	if err := w.saveWorkbook(); err != nil {
+		return err
+	}
+
+	workbook, err := newStreamingWorkbook(w.options)
+	if err != nil {
+		return err
+	}
+	w.workbook = workbook
+	return nil
+}
+
+func (w *streamingExcelRowWriter) Close() ([]string, error) {
+	if err := w.saveWorkbook(); err != nil {
+		return nil, err
+	}
+	return w.paths, nil
+}
+
+// Abort also closes the current workbook, which removes the temp files of its stream writers
+func (w *streamingExcelRowWriter) Abort() error {
+	_ = w.workbook.f.Close()
+	return os.RemoveAll(w.dir)
+}
+
+func (w *streamingExcelRowWriter) saveWorkbook() error {
+	f := w.workbook.f
+	defer f.Close()
+
+	sheets, err := w.workbook.removeEmptySheets()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sheets {
+		if s.option.AutoFilter && len(s.option.ActiveColumns) > 0 {
+			lastCell, _ := excelize.CoordinatesToCellName(len(s.option.ActiveColumns), s.rows)
+			if err := f.AutoFilter(s.name, "A1:"+lastCell, nil); err != nil {
+				log.Println("Error setting autofilter", s.name, err)
+			}
+		}
+
+		if err := s.stream.Flush(); err != nil {
+			log.Println("Error flushing sheet", s.name, err)
+			return err
+		}
+	}
+
+	if w.summary != nil {
+		w.workbook.addSummarySheet(getSummaryRows(*w.summary, w.options, w.workbook.rowsPerOption))
+	}
+	f.SetActiveSheet(0)
+
+	name := "sheets.xlsx"
+	if len(w.paths) > 0 {
+		name = fmt.Sprintf("sheets-%d.xlsx", len(w.paths)+1)
+	}
+
+	path := filepath.Join(w.dir, name)
+	if err := saveStreamingWorkbook(f, path, getHiddenColumns(sheets)); err != nil {
+		log.Println("Error saving file", err)
+		return err
+	}
+
+	w.paths = append(w.paths, path)
+	return nil
+}
+
+// removeEmptySheets deletes the sheets whose key was never in a row, and the default sheet of excelize, then puts the
+// overflow sheets right after their original sheet. like ExcelWriter, a key with an empty list keeps its headers
+func (e *streamingWorkbook) removeEmptySheets() ([]*streamingSheet, error) {
+	kept := make([]*streamingSheet, 0, len(e.sheets))
+	names := make(map[string]bool, len(e.sheets))
+	for _, s := range e.sheets {
+		if !s.hasValue {
+			continue
+		}
+		if s.stream == nil {
+			if err := e.openStream(s); err != nil {
+				return nil, err
+			}
+		}
+		kept = append(kept, s)
+		names[s.name] = true
+	}
+
+	if len(kept) == 0 {
+		return nil, errors.New("no sheet has data to be written")
+	}
+
+	for _, name := range e.f.GetSheetList() {
+		if names[name] {
+			continue
+		}
+		if err := e.f.DeleteSheet(name); err != nil {
+			log.Println("Error deleting sheet", name, err)
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].index != kept[j].index {
+			return kept[i].index < kept[j].index
+		}
+		return kept[i].part < kept[j].part
+	})
+
+	for i := len(kept) - 2; i >= 0; i-- {
+		if err := e.f.MoveSheet(kept[i].name, kept[i+1].name); err != nil {
+			log.Println("Error moving sheet", kept[i].name, err)
+			return nil, err
+		}
+	}
+	return kept, nil
+}
+
+// the summary sheet is small, so it is written without a stream
+func (e *streamingWorkbook) addSummarySheet(rows [][]any) {
+	if _, err := e.f.NewSheet(summarySheetName); err != nil {
+		log.Println("Error adding summary sheet", err)
+		return
+	}
+
+	labelStyle, err := e.f.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "000000", Bold: true, Size: 11, Family: "Calibri"}})
+	if err != nil {
+		log.Println("Error creating summary style", err)
+		return
+	}
+
+	for i, values := range rows {
+		if len(values) == 0 {
+			continue
+		}
+
+		ref, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := e.f.SetSheetRow(summarySheetName, ref, &values); err != nil {
+			log.Println("Error writing summary sheet", err)
+			return
+		}
+		_ = e.f.SetCellStyle(summarySheetName, ref, ref, labelStyle)
+	}
+	_ = e.f.SetColWidth(summarySheetName, "A", "B", 30)
+}
+
+func (e *streamingWorkbook) addSheet(sheetOption domain.PresentationSpecSheetOptions, index int, part int) (*streamingSheet, error) {
+	name := getSheetName(sheetOption.Key, part)
+	if _, err := e.f.NewSheet(name); err != nil {
+		return nil, err
+	}
+
+	s := &streamingSheet{name: name, option: sheetOption, index: index, part: part}
+	e.sheets = append(e.sheets, s)
+	return s, nil
+}
+
+// getSheetWithRoom returns the last sheet of the sheet option, adding an overflow sheet when it is full
+func (e *streamingWorkbook) getSheetWithRoom(i int, maxRowsPerSheet int) (*streamingSheet, error) {
+	last := e.last[i]
+	if last.rows < maxRowsPerSheet {
+		return last, nil
+	}
+
+	s, err := e.addSheet(last.option, i, last.part+1)
+	if err != nil {
+		log.Println("Error adding overflow sheet", last.option.Key, err)
+		return nil, err
+	}
+
+	s.hasValue = true
+	e.last[i] = s
+	return s, nil
+}
+
+// openStream sets the columns and the frozen header, which the stream writer only accepts before the first row,
+// and writes the headers
+func (e *streamingWorkbook) openStream(s *streamingSheet) error {
+	stream, err := e.f.NewStreamWriter(s.name)
+	if err != nil {
+		log.Println("Error opening sheet", s.name, err)
+		return err
+	}
+
+	if s.option.FreezeHeader {
+		err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+		if err != nil {
+			return err
+		}
+	}
+
+	headerStyle, err := e.f.NewStyle(getStreamingHeaderStyle(s.option.HeaderColor))
+	if err != nil {
+		return err
+	}
+
+	headers := make([]any, len(s.option.ActiveColumns))
+	for i, c := range s.option.ActiveColumns {
+		columnOptions := s.option.ColumnOptions[c]
+		label := c
+		if columnOptions.Label != "" {
+			label = columnOptions.Label
+		}
+		headers[i] = label
+
+		// as colunas ocultas são marcadas ao salvar, em saveStreamingWorkbook
+		width := max(xlsx.DefaultAutoWidth(label), streamingMinColumnWidth)
+		if columnOptions.Width > 0 {
+			width = columnOptions.Width
+		}
+		if err := stream.SetColWidth(i+1, i+1, width); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.SetRow("A1", headers, excelize.RowOpts{Height: 20, StyleID: headerStyle}); err != nil {
+		return err
+	}
+
+	s.stream = stream
+	s.rows = 1
+	return nil
+}
+
+func (e *streamingWorkbook) addRow(data map[string]any, s *streamingSheet) error {
+	if s.stream == nil {
+		if err := e.openStream(s); err != nil {
+			return err
+		}
+	}
+
+	s.rows++
+	cells := make([]any, len(s.option.ActiveColumns))
+	for i, c := range s.option.ActiveColumns {
+		cell := excelize.Cell{StyleID: e.cellStyle} // adiciona célula mesmo que não tenha o valor para pular a coluna
+		if value, ok := data[c]; ok {
+			ref, _ := excelize.CoordinatesToCellName(i+1, s.rows)
+			if err := e.setCellValue(&cell, ref, value, s, s.option.ColumnOptions[c]); err != nil {
+				return err
+			}
+		}
+		cells[i] = cell
+	}
+
+	ref, _ := excelize.CoordinatesToCellName(1, s.rows)
+	return s.stream.SetRow(ref, cells)
+}
+
+// setCellValue follows the same typing rules as the setCellValue of ExcelWriter. links are kept in the worksheet
+// until the stream is flushed, past the excel limit of links per sheet they are written as text
+func (e *streamingWorkbook) setCellValue(cell *excelize.Cell, ref string, value any, s *streamingSheet, columnOptions domain.PresentationSpecColumnOptions) error {
+	typed, columnType := getTypedValue(value, columnOptions)
+	cell.Value = typed
+
+	var err error
+	switch columnType {
+	case domain.ColumnTypeNumber:
+		if format := getNumberFormat(typed.(float64), columnOptions.NumberFormat); format != "" {
+			cell.StyleID, err = e.getFormatStyle(format)
+		}
+	case domain.ColumnTypeDate:
+		cell.StyleID, err = e.getFormatStyle(getDateFormat(columnOptions.NumberFormat))
+	case domain.ColumnTypeUrl:
+		if s.links < excelize.TotalSheetHyperlinks {
+			display := typed.(string)
+			err = e.f.SetCellHyperLink(s.name, ref, getLink(display), "External", excelize.HyperlinkOpts{Display: &display})
+			s.links++
+		}
+	}
+	return err
+}
+
+func (e *streamingWorkbook) getFormatStyle(format string) (int, error) {
+	if style, exists := e.styles[format]; exists {
+		return style, nil
+	}
+
+	style, err := e.f.NewStyle(&excelize.Style{
+		Font:         &excelize.Font{Color: "000000", Size: 11, Family: "Calibri"},
+		CustomNumFmt: &format,
+	})
+	if err != nil {
+		log.Println("Error creating number format style", format, err)
+		return 0, err
+	}
+
+	e.styles[format] = style
+	return style, nil
+}
+
+func getStreamingHeaderStyle(color string) *excelize.Style {
+	fill := getHeaderStyle(color).Fill.FgColor
+	return &excelize.Style{
+		Font:      &excelize.Font{Color: "FFFFFF", Bold: true, Size: 12, Family: "Calibri"},
+		Alignment: &excelize.Alignment{Horizontal: "centerContinuous", Vertical: "center"},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{fill[len(fill)-6:]}},
+		Border: []excelize.Border{
+			{Type: "left", Style: 1, Color: "000000"},
+			{Type: "right", Style: 1, Color: "000000"},
+			{Type: "top", Style: 1, Color: "000000"},
+			{Type: "bottom", Style: 1, Color: "000000"},
+		},
+	}
+}
+
+// getHiddenColumns returns the numbers of the hidden columns of each sheet, from 1
+func getHiddenColumns(sheets []*streamingSheet) map[string][]int {
+	hidden := make(map[string][]int)
+	for _, s := range sheets {
+		for i, c := range s.option.ActiveColumns {
+			if s.option.ColumnOptions[c].Hidden {
+				hidden[s.name] = append(hidden[s.name], i+1)
+			}
+		}
+	}
+	return hidden
+}
+
+// saveStreamingWorkbook saves the workbook marking the hidden columns, which the stream writer of excelize 2.9
+// doesn't write. the sheets with hidden columns are copied changing only the <cols> before their rows
+func saveStreamingWorkbook(f *excelize.File, path string, hidden map[string][]int) error {
+	if len(hidden) == 0 {
+		return f.SaveAs(path)
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return err
+	}
+
+	sheetPaths, err := getWorksheetPaths(zr)
+	if err != nil {
+		return err
+	}
+	hiddenByPath := make(map[string][]int, len(hidden))
+	for name, columns := range hidden {
+		hiddenByPath[sheetPaths[name]] = columns
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	for _, zf := range zr.File {
+		columns, exists := hiddenByPath[zf.Name]
+		if !exists {
+			if err := zw.Copy(zf); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := copyHidingColumns(zw, zf, columns); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func copyHidingColumns(zw *zip.Writer, zf *zip.File, columns []int) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: zf.Name, Method: zip.Deflate, Modified: zf.Modified})
+	if err != nil {
+		return err
+	}
+
+	// as colunas ficam antes de <sheetData>, o resto da aba é copiado sem ser lido para a memória
+	reader := bufio.NewReader(rc)
+	var head strings.Builder
+	for {
+		tag, err := reader.ReadString('>')
+		head.WriteString(tag)
+		if err == io.EOF || strings.Contains(tag, "<sheetData") {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	cols := head.String()
+	for _, column := range columns {
+		col := fmt.Sprintf(`<col min="%d" max="%d"`, column, column)
+		cols = strings.Replace(cols, col, col+` hidden="1"`, 1)
+	}
+
+	if _, err := io.WriteString(w, cols); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// getWorksheetPaths reads the path in the package of each sheet from the workbook and its relationships
+func getWorksheetPaths(zr *zip.Reader) (map[string]string, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var relationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	if err := readZipXML(zr, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if err := readZipXML(zr, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(relationships.Relationships))
+	for _, relationship := range relationships.Relationships {
+		target := relationship.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = "xl/" + target
+		}
+		targets[relationship.ID] = target
+	}
+
+	paths := make(map[string]string, len(workbook.Sheets))
+	for _, sheet := range workbook.Sheets {
+		paths[sheet.Name] = targets[sheet.RID]
+	}
+	return paths, nil
+}
+
+func readZipXML(zr *zip.Reader, name string, v any) error {
+	rc, err := zr.Open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,183 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func readExcelizeRows(t *testing.T, path string) map[string][][]string {
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	sheets := make(map[string][][]string)
+	for _, name := range f.GetSheetList() {
+		rows, err := f.GetRows(name)
+		require.NoError(t, err)
+		sheets[name] = rows
+	}
+	return sheets
+}
+
+// excelizeWorkbook is what the tests compare between the workbooks of ExcelWriter and StreamingExcelWriter
+type excelizeWorkbook struct {
+	Sheets []string
+	Rows   map[string][][]string
+	Hidden map[string][]bool
+	Links  map[string]map[string]string
+	Panes  map[string]bool
+}
+
+func readExcelizeWorkbook(t *testing.T, path string) excelizeWorkbook {
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	workbook := excelizeWorkbook{
+		Sheets: f.GetSheetList(),
+		Rows:   readExcelizeRows(t, path),
+		Hidden: map[string][]bool{},
+		Links:  map[string]map[string]string{},
+		Panes:  map[string]bool{},
+	}
+	for _, name := range workbook.Sheets {
+		workbook.Links[name] = map[string]string{}
+		for i, row := range workbook.Rows[name] {
+			for j := range row {
+				ref, _ := excelize.CoordinatesToCellName(j+1, i+1)
+				if ok, link, err := f.GetCellHyperLink(name, ref); err == nil && ok {
+					workbook.Links[name][ref] = link
+				}
+			}
+		}
+
+		if len(workbook.Rows[name]) > 0 {
+			for j := range workbook.Rows[name][0] {
+				column, _ := excelize.ColumnNumberToName(j + 1)
+				visible, err := f.GetColVisible(name, column)
+				require.NoError(t, err)
+				workbook.Hidden[name] = append(workbook.Hidden[name], !visible)
+			}
+		}
+
+		panes, err := f.GetPanes(name)
+		require.NoError(t, err)
+		workbook.Panes[name] = panes.Freeze
+	}
+	return workbook
+}
+
+func TestStreamingExcelWriter_Write(t *testing.T) {
+	data := []map[string]any{{
+		"RFB": map[string]any{
+			"CNPJ":           float64(35965725000107),
+			"Titulo":         "RAZAO SOCIAL",
+			"Capital Social": "110000.5",
+			"Abertura":       "10-01-2020",
+			"Site":           "https://driva.io",
+		},
+		"Telefones": []any{
+			map[string]any{"Telefone": "123456"},
+			map[string]any{"Telefone": "654321"},
+		},
+		"Socios": []any{},
+	}, {
+		"RFB": map[string]any{"CNPJ": float64(11111111000111), "Titulo": "OUTRA RAZAO"},
+	}}
+
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{
+				Key:           "RFB",
+				ActiveColumns: []string{"CNPJ", "Titulo", "Capital Social", "Abertura", "Site", "Interno"},
+				Position:      1,
+				FreezeHeader:  true,
+				AutoFilter:    true,
+				ColumnOptions: map[string]domain.PresentationSpecColumnOptions{
+					"Titulo":         {Label: "Razão Social"},
+					"Capital Social": {Type: domain.ColumnTypeNumber, NumberFormat: "0.00"},
+					"Abertura":       {Type: domain.ColumnTypeDate, NumberFormat: "yyyy-mm-dd"},
+					"Interno":        {Hidden: true},
+				},
+			},
+			{Key: "Socios", ActiveColumns: []string{"Nome"}, Position: 2, ShouldExplode: true},
+			{Key: "Telefones", ActiveColumns: []string{"Telefone"}, Position: 3, ShouldExplode: true},
+		},
+	}
+
+	t.Run("Should write the same sheets and values as ExcelWriter", func(t *testing.T) {
+		expectedPaths, err := (&ExcelWriter{}).Write(data, spec)
+		require.NoError(t, err)
+		paths, err := (&StreamingExcelWriter{}).Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+
+		expected := readExcelizeWorkbook(t, expectedPaths[0])
+		actual := readExcelizeWorkbook(t, paths[0])
+		assert.Equal(t, expected, actual)
+
+		// o que a comparação cobre, para que ela não passe com as duas planilhas vazias
+		assert.Equal(t, []string{"RFB", "Socios", "Telefones"}, actual.Sheets)
+		assert.Equal(t, [][]string{{"Nome"}}, actual.Rows["Socios"])
+		assert.Equal(t, []bool{false, false, false, false, false, true}, actual.Hidden["RFB"])
+		assert.Equal(t, map[string]string{"E2": "https://driva.io"}, actual.Links["RFB"])
+		assert.True(t, actual.Panes["RFB"])
+	})
+
+	t.Run("Should continue full sheets and workbooks like ExcelWriter", func(t *testing.T) {
+		expectedPaths, err := (&ExcelWriter{MaxRowsPerSheet: 2, MaxRowsPerWorkbook: 3}).Write(data, spec)
+		require.NoError(t, err)
+		ew := StreamingExcelWriter{MaxRowsPerSheet: 2, MaxRowsPerWorkbook: 3}
+		paths, err := ew.Write(data, spec)
+		require.NoError(t, err)
+		require.Len(t, paths, 2)
+
+		for i := range paths {
+			assert.Equal(t, readExcelizeWorkbook(t, expectedPaths[i]), readExcelizeWorkbook(t, paths[i]))
+		}
+
+		first := readExcelizeRows(t, paths[0])
+		assert.Len(t, first, 4)
+		assert.Len(t, first["Telefones (2)"], 2)
+
+		second := readExcelizeRows(t, paths[1])
+		assert.Equal(t, "11111111000111", second["RFB"][1][0])
+	})
+
+	t.Run("Should fail when no sheet has data", func(t *testing.T) {
+		_, err := (&StreamingExcelWriter{}).Write([]map[string]any{{"Outra": map[string]any{}}}, spec)
+		assert.Error(t, err)
+	})
+}
+
+func TestGetStreamingHeaderStyle(t *testing.T) {
+	assert.Equal(t, []string{"407AD6"}, getStreamingHeaderStyle("").Fill.Color)
+	assert.Equal(t, []string{"00AA00"}, getStreamingHeaderStyle("#00aa00").Fill.Color)
+}
+
+func TestStreamingExcelWriter_Summary(t *testing.T) {
+	spec := domain.PresentationSpec{
+		SheetOptions: []domain.PresentationSpecSheetOptions{
+			{Key: "RFB", ActiveColumns: []string{"CNPJ"}, Position: 1},
+		},
+	}
+
+	rowWriter, err := (&StreamingExcelWriter{}).Open(spec)
+	require.NoError(t, err)
+	rowWriter.(ports.SummaryRowWriter).SetSummary(ports.ExportSummary{ListName: "Minha lista", UserName: "Maria"})
+	require.NoError(t, rowWriter.WriteRow(map[string]any{"RFB": map[string]any{"CNPJ": "111111"}}))
+
+	paths, err := rowWriter.Close()
+	require.NoError(t, err)
+
+	sheets := readExcelizeRows(t, paths[0])
+	require.Len(t, sheets, 2)
+	assert.Equal(t, []string{"Lista", "Minha lista"}, sheets[summarySheetName][0])
+	assert.Equal(t, []string{"Solicitado por", "Maria"}, sheets[summarySheetName][3])
+	assert.Equal(t, []string{"RFB", "1"}, sheets[summarySheetName][8])
+}
@@ -0,0 +1,36 @@
+package writers
+
+import (
+	"export-service/internal/core/domain"
+	"export-service/internal/core/ports"
+	"fmt"
+)
+
+const summarySheetName = "Sobre esta exportação"
+
+const summaryTimeLayout = "02/01/2006 15:04:05 MST"
+
+// getSummaryRows lists the export details and how many rows each sheet option got in the workbook,
+// overflow sheets included
+func getSummaryRows(summary ports.ExportSummary, options []domain.PresentationSpecSheetOptions, rows []int) [][]any {
+	requestedBy := summary.UserName
+	if summary.UserEmail != "" {
+		requestedBy = fmt.Sprintf("%s (%s)", summary.UserName, summary.UserEmail)
+	}
+
+	summaryRows := [][]any{
+		{"Lista", summary.ListName},
+		{"Exportado em", summary.ExportedAt.Format(summaryTimeLayout)},
+		{"Fonte dos dados", summary.DataSource},
+		{"Solicitado por", requestedBy},
+		{"Presentation spec", summary.PresentationSpecID},
+		{"Versão do presentation spec", summary.PresentationSpecVersion},
+		{},
+		{"Aba", "Linhas"},
+	}
+
+	for i, option := range options {
+		summaryRows = append(summaryRows, []any{option.Key, rows[i]})
+	}
+	return summaryRows
+}